@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	in := subkstruct{
+		K:         "v",
+		K1:        1,
+		K1_1:      1.1,
+		Ktrue:     true,
+		Ktime:     time.Now().UTC().Round(0),
+		Kduration: time.Second,
+	}
+	for _, codec := range []Codec{JSONCodec, GobCodec} {
+		bytes, err := codec.Marshal(in)
+		if err != nil {
+			t.Fatalf("%s: %v", codec.Name(), err)
+		}
+		var out subkstruct
+		err = codec.Unmarshal(bytes, &out)
+		if err != nil {
+			t.Fatalf("%s: %v", codec.Name(), err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("%s: got %+v, want %+v", codec.Name(), out, in)
+		}
+	}
+}
+
+func TestClient_UseCodec(t *testing.T) {
+	in := []subkstruct{{
+		K:         "v",
+		K1:        1,
+		K1_1:      1.1,
+		Ktrue:     true,
+		Ktime:     time.Now().UTC().Round(0),
+		Kduration: time.Second,
+	}}
+	err := client.SetSingleValue(context.Background(), "kcodec[]struct", in, UseCodec(GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []subkstruct
+	err = client.GetSingleValue(context.Background(), "kcodec[]struct", &out, UseCodec(GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	gobClient := NewRedisClient(client.Cmdable, UseCodec(GobCodec))
+	err = gobClient.SetStructValue(context.Background(), "kcodecstruct", kstruct{Kstruct: in[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kstruct kstruct
+	err = gobClient.GetStructValue(context.Background(), "kcodecstruct", &kstruct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in[0], kstruct.Kstruct) {
+		t.Errorf("got %+v, want %+v", kstruct.Kstruct, in[0])
+	}
+}
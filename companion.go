@@ -0,0 +1,175 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var errCompanionCond = errors.New("redis: structs with companion keys can't be written conditionally")
+
+// companionKey returns the key holding a companion field of the struct stored at key.
+func companionKey(key string, field *structField) string {
+	return key + ":" + field.key
+}
+
+// companionWrites returns the companion keys of a struct and their writes,
+// nil and empty fields have no arguments.
+func companionWrites(key string, valValue reflect.Value, plan *structPlan, options *Options) (keys []string, writes []keyWrite, err error) {
+	keys = make([]string, len(plan.companions))
+	writes = make([]keyWrite, len(plan.companions))
+	for i := range plan.companions {
+		field := &plan.companions[i]
+		keys[i] = companionKey(key, field)
+		writes[i].kind = field.companion
+		fieldValue, ok := fieldByIndex(valValue, field.index)
+		if !ok || isNilValue(fieldValue) {
+			continue
+		}
+		fieldValue = reflect.Indirect(fieldValue)
+		switch field.companion {
+		case kindList, kindSet, kindZSet:
+			if fieldValue.Kind() != reflect.Slice && fieldValue.Kind() != reflect.Array {
+				return nil, nil, unsupportedTypeError(fieldValue, "slice or array")
+			}
+			if field.companion == kindZSet {
+				writes[i].args, err = zsetValues(fieldValue, options)
+			} else {
+				writes[i].args, err = sliceValues(fieldValue, options)
+			}
+		case kindHash:
+			switch fieldValue.Kind() {
+			case reflect.Map:
+				var m map[string]interface{}
+				m, err = mapValues(fieldValue, options)
+				writes[i].args = hashArgs(m)
+			case reflect.Struct:
+				// the companion is replaced, there are no fields to remove
+				structOptions := *options
+				structOptions.WriteMode = Replace
+				structOptions.Fields, structOptions.Omit = nil, nil
+				writes[i], err = structWrite(fieldValue, cachedStructPlan(fieldValue.Type(), options), &structOptions)
+			default:
+				return nil, nil, unsupportedTypeError(fieldValue, "map or struct")
+			}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return keys, writes, nil
+}
+
+// queueCompanions queues the companion writes, each companion key is replaced
+// as a whole and gets the TTL of the struct.
+func queueCompanions(ctx context.Context, pipe redis.Pipeliner, keys []string, writes []keyWrite, options *Options) {
+	for i, key := range keys {
+		pipe.Del(ctx, key)
+		if len(writes[i].args) == 0 {
+			continue
+		}
+		writes[i].queue(ctx, pipe, key)
+		if options.Expiration > 0 {
+			pipe.Expire(ctx, key, options.Expiration)
+		}
+	}
+}
+
+// writeWithCompanions writes the hash of a struct and its companion keys in one transaction.
+func (c *Client) writeWithCompanions(ctx context.Context, key string, valValue reflect.Value, plan *structPlan, options Options, w keyWrite) (res SetResult, err error) {
+	if options.conditional() {
+		return res, errCompanionCond
+	}
+	keys, writes, err := companionWrites(key, valValue, plan, &options)
+	if err != nil {
+		return res, err
+	}
+	err = c.txPipelined(ctx, func(pipe redis.Pipeliner) error {
+		w.queueKey(ctx, pipe, key, &options)
+		queueCompanions(ctx, pipe, keys, writes, &options)
+		return nil
+	})
+	return SetResult{Written: err == nil}, err
+}
+
+// readCompanions queues the reads of the companion keys of the struct stored at key.
+func readCompanions(ctx context.Context, pipe redis.Cmdable, key string, plan *structPlan) []redis.Cmder {
+	cmds := make([]redis.Cmder, len(plan.companions))
+	for i := range plan.companions {
+		field := &plan.companions[i]
+		companion := companionKey(key, field)
+		switch field.companion {
+		case kindList:
+			cmds[i] = pipe.LRange(ctx, companion, 0, -1)
+		case kindSet:
+			cmds[i] = pipe.SMembers(ctx, companion)
+		case kindZSet:
+			cmds[i] = pipe.ZRangeWithScores(ctx, companion, 0, -1)
+		case kindHash:
+			cmds[i] = pipe.HGetAll(ctx, companion)
+		}
+	}
+	return cmds
+}
+
+// decodeCompanions sets the companion fields of a struct from their reads, found reports
+// whether any companion key exists. Fields of missing companion keys are left alone.
+func decodeCompanions(valValue reflect.Value, plan *structPlan, cmds []redis.Cmder, options *Options) (found bool, errs DecodeErrors) {
+	for i, cmd := range cmds {
+		field := &plan.companions[i]
+		var err error
+		switch cmd := cmd.(type) {
+		case *redis.StringSliceCmd:
+			vals := cmd.Val()
+			if len(vals) == 0 {
+				continue
+			}
+			fieldValue := companionValue(valValue, field)
+			if fieldValue.Kind() == reflect.Array {
+				if len(vals) > fieldValue.Len() {
+					vals = vals[:fieldValue.Len()]
+				}
+				err = setArray(vals, fieldValue, options)
+			} else {
+				err = setSlice(vals, fieldValue, options)
+			}
+		case *redis.ZSliceCmd:
+			zs := cmd.Val()
+			if len(zs) == 0 {
+				continue
+			}
+			// the scores were taken out of the members on write
+			err = setZSet(zs, companionValue(valValue, field), options, true)
+		case *redis.StringStringMapCmd:
+			hash := cmd.Val()
+			if len(hash) == 0 {
+				continue
+			}
+			fieldValue := companionValue(valValue, field)
+			if fieldValue.Kind() == reflect.Map {
+				err = setMap(hash, fieldValue, options)
+			} else {
+				err = cachedStructPlan(fieldValue.Type(), options).decodeHash(fieldValue, hash, options)
+			}
+		}
+		found = true
+		if err != nil {
+			errs = append(errs, entryErrors(err, field.key)...)
+		}
+	}
+	return found, errs
+}
+
+// companionValue returns the settable value of a companion field, allocating pointers.
+func companionValue(valValue reflect.Value, field *structField) reflect.Value {
+	fieldValue := fieldByIndexAlloc(valValue, field.index)
+	if fieldValue.Kind() == reflect.Ptr {
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+		}
+		fieldValue = fieldValue.Elem()
+	}
+	return fieldValue
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type companionsub struct {
+	Name string `json:"name"`
+}
+
+type companionstruct struct {
+	Name    string          `json:"name"`
+	Tags    []string        `json:"tags,list"`
+	Roles   []string        `json:"roles,set"`
+	Counts  map[string]int  `json:"counts,hash"`
+	Sub     *companionsub   `json:"sub,hash"`
+	Members []zsetmember    `json:"members,zset"`
+	Subs    []companionsub  `json:"subs,list"`
+	Empty   []string        `json:"empty,list"`
+	Inline  [2]companionsub `json:"inline"`
+}
+
+func TestClient_CompanionKeys(t *testing.T) {
+	ctx := context.Background()
+	key := "kcompanion"
+	defer client.DelValue(ctx, key, companionstruct{})
+
+	in := companionstruct{
+		Name:    "parent",
+		Tags:    []string{"b", "a", "b"},
+		Roles:   []string{"admin"},
+		Counts:  map[string]int{"x": 1, "y": 2},
+		Sub:     &companionsub{"child"},
+		Members: []zsetmember{{"a", 1}, {"b", 2}},
+		Subs:    []companionsub{{"s1"}, {"s2"}},
+		Inline:  [2]companionsub{{"i1"}, {"i2"}},
+	}
+	err := client.SetStructValue(ctx, key, in, Expiration(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != 2 || hash["name"] != "parent" {
+		t.Errorf("got hash %v", hash)
+	}
+	if tags, _ := client.LRange(ctx, key+":tags", 0, -1).Result(); !reflect.DeepEqual(tags, in.Tags) {
+		t.Errorf("got tags %v", tags)
+	}
+	if ttl, _ := client.TTL(ctx, key+":counts").Result(); ttl <= 0 {
+		t.Errorf("got ttl %v", ttl)
+	}
+	if n, _ := client.Exists(ctx, key+":empty").Result(); n != 0 {
+		t.Errorf("empty companion exists")
+	}
+
+	var out companionstruct
+	if err = client.GetStructValue(ctx, key, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v", out)
+	}
+
+	// companions are replaced as a whole
+	in.Tags = []string{"c"}
+	if err = client.SetStructValue(ctx, key, in); err != nil {
+		t.Fatal(err)
+	}
+	out = companionstruct{}
+	if err = client.GetStructValue(ctx, key, &out, Strict()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Tags, []string{"c"}) {
+		t.Errorf("got tags %v", out.Tags)
+	}
+
+	if err = client.DelValue(ctx, key, &companionstruct{}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.Exists(ctx, key, key+":tags", key+":roles", key+":counts", key+":sub", key+":members", key+":subs").Result(); n != 0 {
+		t.Errorf("%d keys left", n)
+	}
+	if err = client.GetStructValue(ctx, key, &out); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v", err)
+	}
+}
+
+type companionelement struct {
+	ID   string   `json:"id,pk"`
+	Tags []string `json:"tags,set"`
+}
+
+func TestClient_CompanionElementHashes(t *testing.T) {
+	ctx := context.Background()
+	key := "kcompanionelements"
+	defer client.Del(ctx, key, key+":e1", key+":e1:tags", key+":e2", key+":e2:tags")
+
+	in := []companionelement{{"e1", []string{"t"}}, {"e2", nil}}
+	err := client.SetSliceValue(ctx, key, in, ElementHashes(""), UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []companionelement
+	if err = client.GetSliceValue(ctx, key, &out, ElementHashes("")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v", out)
+	}
+}
+
+func TestClient_CompanionErrors(t *testing.T) {
+	ctx := context.Background()
+	key := "kcompanionerr"
+	defer client.DelValue(ctx, key, companionstruct{})
+
+	err := client.SetStructValue(ctx, key, companionstruct{Name: "a"}, IfAbsent())
+	if !errors.Is(err, errCompanionCond) {
+		t.Errorf("got %v", err)
+	}
+	err = client.SetStructValue(ctx, key, struct {
+		Tags string `json:"tags,list"`
+	}{"a"})
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v", err)
+	}
+
+	client.RPush(ctx, key+":tags", "a")
+	client.HSet(ctx, key+":counts", "x", "nan")
+	var out companionstruct
+	err = client.GetStructValue(ctx, key, &out)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Field != "counts/x" || decodeErr.Key != key {
+		t.Errorf("got %v", err)
+	}
+	if !reflect.DeepEqual(out.Tags, []string{"a"}) {
+		t.Errorf("got tags %v", out.Tags)
+	}
+}
@@ -0,0 +1,218 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SetResult is the outcome of a write.
+type SetResult struct {
+	// Written is false when the IfAbsent or IfPresent condition didn't hold.
+	Written bool
+	// Existed reports whether the key existed before the write,
+	// strings written without condition nor GetPrevious always report false.
+	Existed bool
+}
+
+var errPipelinedCond = errors.New("redis: conditional writes can't be queued on a pipeline")
+
+// conditional reports whether the write needs SET flags or a script.
+func (opt *Options) conditional() bool {
+	return opt.Condition != WriteAlways || opt.KeepTTL || opt.previous != nil
+}
+
+// setStringCond writes a string with the NX, XX, KEEPTTL and GET flags of SET.
+func (c *Client) setStringCond(ctx context.Context, key string, bytes []byte, options Options) (res SetResult, err error) {
+	if _, ok := c.Cmdable.(redis.Pipeliner); ok {
+		return res, errPipelinedCond
+	}
+	args := redis.SetArgs{KeepTTL: options.KeepTTL, Get: options.previous != nil}
+	if !options.KeepTTL && options.Expiration > 0 {
+		args.TTL = options.Expiration
+	}
+	switch options.Condition {
+	case WriteIfAbsent:
+		args.Mode = "NX"
+	case WriteIfPresent:
+		args.Mode = "XX"
+	}
+	val, err := c.SetArgs(ctx, key, bytes, args).Result()
+	if err != nil && err != redis.Nil {
+		return res, err
+	}
+	// nil is the missing previous value with GET, the unmet condition without
+	found := err != redis.Nil
+	if !args.Get {
+		res.Written = found
+		res.Existed = options.Condition == WriteIfPresent && found || options.Condition == WriteIfAbsent && !found
+		return res, nil
+	}
+	res.Existed = found
+	switch options.Condition {
+	case WriteIfAbsent:
+		res.Written = !found
+	case WriteIfPresent:
+		res.Written = found
+	default:
+		res.Written = true
+	}
+	if !found {
+		return res, nil
+	}
+	previous, err := previousValue(options)
+	if err != nil {
+		return res, err
+	}
+	return res, decodeError(setValueByString(previous, val, &options), key)
+}
+
+// writeKeyScript writes a collection key when the condition holds.
+// KEYS[1] is the key, ARGV holds the condition, keepttl, the TTL in milliseconds,
+// get, replace, the kind of key, the number of arguments of the write,
+// the arguments and the hash fields to remove.
+// It returns whether the key was written, whether it existed and its previous content.
+var writeKeyScript = redis.NewScript(`
+local key = KEYS[1]
+local condition, keepttl, ttl = ARGV[1], ARGV[2] == '1', tonumber(ARGV[3])
+local get, replace, kind, nargs = ARGV[4] == '1', ARGV[5] == '1', ARGV[6], tonumber(ARGV[7])
+local existed = redis.call('EXISTS', key) == 1
+local previous = {}
+if get and existed then
+	if kind == 'hash' then
+		previous = redis.call('HGETALL', key)
+	elseif kind == 'list' then
+		previous = redis.call('LRANGE', key, 0, -1)
+	elseif kind == 'zset' then
+		previous = redis.call('ZRANGE', key, 0, -1)
+	else
+		previous = redis.call('SMEMBERS', key)
+	end
+end
+local found = existed and 1 or 0
+if (condition == 'nx' and existed) or (condition == 'xx' and not existed) then
+	return {0, found, previous}
+end
+local pttl = -1
+if keepttl then
+	pttl = redis.call('PTTL', key)
+end
+if replace then
+	redis.call('DEL', key)
+end
+-- unpack fails past a few thousand values, commands get the arguments by chunks,
+-- of an even size to keep the field value and score member pairs together
+local function chunked(cmd, first, last)
+	for i = first, last, 1000 do
+		redis.call(cmd, key, unpack(ARGV, i, math.min(i + 999, last)))
+	end
+end
+if nargs > 0 then
+	chunked(({hash = 'HSET', list = 'RPUSH', set = 'SADD', zset = 'ZADD'})[kind], 8, 7 + nargs)
+end
+if #ARGV > 7 + nargs then
+	chunked('HDEL', 8 + nargs, #ARGV)
+end
+if keepttl then
+	if pttl > 0 then
+		redis.call('PEXPIRE', key, pttl)
+	end
+elseif ttl > 0 then
+	redis.call('PEXPIRE', key, ttl)
+end
+return {1, found, previous}
+`)
+
+// writeKeyCond writes a collection key with writeKeyScript.
+func (c *Client) writeKeyCond(ctx context.Context, key string, options Options, w keyWrite) (res SetResult, err error) {
+	if _, ok := c.Cmdable.(redis.Pipeliner); ok {
+		return res, errPipelinedCond
+	}
+	condition := ""
+	switch options.Condition {
+	case WriteIfAbsent:
+		condition = "nx"
+	case WriteIfPresent:
+		condition = "xx"
+	}
+	var ttl int64
+	if options.Expiration > 0 {
+		ttl = options.Expiration.Milliseconds()
+	}
+	args := make([]interface{}, 0, 7+len(w.args)+len(w.removed))
+	args = append(args, condition, flag(options.KeepTTL), ttl, flag(options.previous != nil),
+		flag(options.WriteMode == Replace), w.kind, len(w.args))
+	args = append(args, w.args...)
+	for _, field := range w.removed {
+		args = append(args, field)
+	}
+	val, err := writeKeyScript.Run(ctx, c.Cmdable, []string{key}, args...).Result()
+	if err != nil {
+		return res, err
+	}
+	reply, ok := val.([]interface{})
+	if !ok || len(reply) != 3 {
+		return res, fmt.Errorf("redis: unexpected reply %v", val)
+	}
+	res.Written = reply[0].(int64) == 1
+	res.Existed = reply[1].(int64) == 1
+	if options.previous == nil || !res.Existed {
+		return res, nil
+	}
+	return res, decodeError(decodePrevious(w.kind, reply[2].([]interface{}), options), key)
+}
+
+func flag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// previousValue returns the value GetPrevious decodes into.
+func previousValue(options Options) (reflect.Value, error) {
+	previous := reflect.ValueOf(options.previous)
+	if previous.Kind() != reflect.Ptr || previous.IsNil() {
+		return previous, ErrNotPointer
+	}
+	return previous.Elem(), nil
+}
+
+// decodePrevious decodes the previous content of a collection key:
+// hashes into structs or maps, lists and sets into slices or arrays.
+func decodePrevious(kind string, vals []interface{}, options Options) error {
+	previous, err := previousValue(options)
+	if err != nil {
+		return err
+	}
+	strs := make([]string, len(vals))
+	for i, val := range vals {
+		strs[i], _ = val.(string)
+	}
+	if kind == kindHash {
+		hash := make(map[string]string, len(strs)/2)
+		for i := 0; i+1 < len(strs); i += 2 {
+			hash[strs[i]] = strs[i+1]
+		}
+		switch previous.Kind() {
+		case reflect.Struct:
+			return cachedStructPlan(previous.Type(), &options).decodeHash(previous, hash, &options)
+		case reflect.Map:
+			return setMap(hash, previous, &options)
+		}
+		return unsupportedTypeError(previous, "struct or map")
+	}
+	switch previous.Kind() {
+	case reflect.Slice:
+		return setSlice(strs, previous, &options)
+	case reflect.Array:
+		if len(strs) > previous.Len() {
+			strs = strs[:previous.Len()]
+		}
+		return setArray(strs, previous, &options)
+	}
+	return unsupportedTypeError(previous, "slice or array")
+}
@@ -0,0 +1,208 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClient_SetValueResultString(t *testing.T) {
+	ctx := context.Background()
+	key := "kcondstring"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	res, err := client.SetValueResult(ctx, key, 1, IfPresent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Written || res.Existed {
+		t.Errorf("got %+v", res)
+	}
+	res, err = client.SetValueResult(ctx, key, 1, IfAbsent(), Expiration(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Written || res.Existed {
+		t.Errorf("got %+v", res)
+	}
+	res, err = client.SetValueResult(ctx, key, 2, IfAbsent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Written || !res.Existed {
+		t.Errorf("got %+v", res)
+	}
+
+	var previous int
+	res, err = client.SetValueResult(ctx, key, 3, IfPresent(), KeepTTL(), GetPrevious(&previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Written || !res.Existed || previous != 1 {
+		t.Errorf("got %+v, previous %d", res, previous)
+	}
+	if ttl, _ := client.TTL(ctx, key).Result(); ttl <= 0 {
+		t.Errorf("ttl = %v, want kept", ttl)
+	}
+	var n int
+	if err = client.GetValue(ctx, key, &n); err != nil || n != 3 {
+		t.Errorf("got %d, %v", n, err)
+	}
+
+	// SetValue honors the conditions too
+	if err = client.SetValue(ctx, key, 4, IfAbsent()); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.GetValue(ctx, key, &n); err != nil || n != 3 {
+		t.Errorf("got %d, %v", n, err)
+	}
+}
+
+func TestClient_SetValueResultHash(t *testing.T) {
+	ctx := context.Background()
+	key := "kcondhash"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	in := subkstruct{K: "v", K1: 1}
+	res, err := client.SetValueResult(ctx, key, in, IfPresent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Written || res.Existed {
+		t.Errorf("got %+v", res)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("key should not be written")
+	}
+	res, err = client.SetValueResult(ctx, key, in, IfAbsent(), Expiration(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Written || res.Existed {
+		t.Errorf("got %+v", res)
+	}
+	if ttl, _ := client.TTL(ctx, key).Result(); ttl <= 0 {
+		t.Errorf("ttl = %v, want > 0", ttl)
+	}
+	res, err = client.SetValueResult(ctx, key, subkstruct{K: "w"}, IfAbsent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Written || !res.Existed {
+		t.Errorf("got %+v", res)
+	}
+
+	client.HSet(ctx, key, "stale", "v")
+	var previous subkstruct
+	res, err = client.SetValueResult(ctx, key, subkstruct{K: "w", K1: 2}, IfPresent(), KeepTTL(),
+		UseWriteMode(Replace), GetPrevious(&previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Written || !res.Existed || previous.K != "v" || previous.K1 != 1 {
+		t.Errorf("got %+v, previous %+v", res, previous)
+	}
+	if ttl, _ := client.TTL(ctx, key).Result(); ttl <= 0 {
+		t.Errorf("ttl = %v, want kept", ttl)
+	}
+	if ok, _ := client.HExists(ctx, key, "stale").Result(); ok {
+		t.Error("stale field should be removed")
+	}
+	var out subkstruct
+	if err = client.GetStructValue(ctx, key, &out); err != nil || out.K != "w" || out.K1 != 2 {
+		t.Errorf("got %+v, %v", out, err)
+	}
+
+	var previousMap map[string]string
+	_, err = client.SetValueResult(ctx, key, map[string]string{"k": "x"}, GetPrevious(&previousMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previousMap["k"] != "w" {
+		t.Errorf("got %v", previousMap)
+	}
+}
+
+func TestClient_SetValueResultList(t *testing.T) {
+	ctx := context.Background()
+	key := "kcondlist"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.RPush(ctx, key, "a", "b")
+
+	var previous []string
+	res, err := client.SetValueResult(ctx, key, []string{"c"}, IfPresent(), GetPrevious(&previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Written || !res.Existed || !reflect.DeepEqual(previous, []string{"a", "b"}) {
+		t.Errorf("got %+v, previous %v", res, previous)
+	}
+	list, _ := client.LRange(ctx, key, 0, -1).Result()
+	if !reflect.DeepEqual(list, []string{"a", "b", "c"}) {
+		t.Errorf("got %v", list)
+	}
+
+	key = "kcondset"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	err = client.SetSliceValue(ctx, key, []int{1, 2}, RedisTypeSet(), IfAbsent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.SetSliceValue(ctx, key, []int{3}, RedisTypeSet(), IfAbsent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, _ := client.SMembers(ctx, key).Result()
+	sort.Strings(members)
+	if !reflect.DeepEqual(members, []string{"1", "2"}) {
+		t.Errorf("got %v", members)
+	}
+}
+
+func TestClient_SetValueResultLarge(t *testing.T) {
+	ctx := context.Background()
+	key := "kcondlarge"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	// more values than the unpack of the script takes at once
+	list := make([]int, 10001)
+	hash := make(map[string]int, len(list))
+	for i := range list {
+		list[i] = i
+		hash["f"+strconv.Itoa(i)] = i
+	}
+	res, err := client.SetValueResult(ctx, key, list, IfAbsent())
+	if err != nil || !res.Written {
+		t.Fatalf("got %+v, %v", res, err)
+	}
+	if n, _ := client.LLen(ctx, key).Result(); n != int64(len(list)) {
+		t.Errorf("got %d members", n)
+	}
+
+	client.Del(ctx, key)
+	var previous map[string]int
+	res, err = client.SetValueResult(ctx, key, hash, KeepTTL(), GetPrevious(&previous))
+	if err != nil || !res.Written {
+		t.Fatalf("got %+v, %v", res, err)
+	}
+	if n, _ := client.HLen(ctx, key).Result(); n != int64(len(hash)) {
+		t.Errorf("got %d fields", n)
+	}
+}
+
+func TestClient_SetValueResultPipeline(t *testing.T) {
+	c := NewRedisClient(client.Pipeline())
+	_, err := c.SetValueResult(context.Background(), "kcondpipeline", 1, IfAbsent())
+	if !errors.Is(err, errPipelinedCond) {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"reflect"
+	"sync"
+)
+
+// RedisMarshaler is implemented by types that control their own redis representation.
+// It takes precedence over the encoding interfaces and the Codec.
+type RedisMarshaler interface {
+	MarshalRedis() ([]byte, error)
+}
+
+// RedisUnmarshaler is implemented by types that decode the representation written by MarshalRedis.
+type RedisUnmarshaler interface {
+	UnmarshalRedis(data []byte) error
+}
+
+var (
+	redisMarshalerType   = reflect.TypeOf((*RedisMarshaler)(nil)).Elem()
+	redisUnmarshalerType = reflect.TypeOf((*RedisUnmarshaler)(nil)).Elem()
+)
+
+// Converter controls the redis representation of a type that can't implement
+// RedisMarshaler and RedisUnmarshaler, e.g. a third-party type.
+type Converter struct {
+	// Marshal receives a value of the registered type.
+	Marshal func(v interface{}) ([]byte, error)
+	// Unmarshal receives a pointer to a value of the registered type.
+	Unmarshal func(data []byte, v interface{}) error
+}
+
+type converterRegistry struct {
+	converters sync.Map // map[reflect.Type]Converter
+	// plans are the struct plans built with the converters.
+	plans sync.Map // map[structPlanKey]*structPlan
+}
+
+func newConverterRegistry() *converterRegistry {
+	return &converterRegistry{}
+}
+
+func (r *converterRegistry) lookup(valType reflect.Type) (converter Converter, ok bool) {
+	if r == nil {
+		return converter, false
+	}
+	v, ok := r.converters.Load(valType)
+	if !ok {
+		return converter, false
+	}
+	return v.(Converter), true
+}
+
+// RegisterConverter registers the converter for the type of value,
+// it takes precedence over RedisMarshaler and all the other encodings.
+// Converters apply to named types and should be registered before the client is used.
+func (c *Client) RegisterConverter(value interface{}, converter Converter) {
+	r := c.options.converters
+	r.converters.Store(reflect.TypeOf(value), converter)
+	// plans built before may have flattened or encoded the type differently
+	r.plans.Range(func(key, _ interface{}) bool {
+		r.plans.Delete(key)
+		return true
+	})
+}
+
+// isRedisScalar reports whether values of valType are stored as a redis string
+// by a converter or their RedisMarshaler, whatever their kind.
+func isRedisScalar(valType reflect.Type, options *Options) bool {
+	if _, ok := options.converters.lookup(valType); ok {
+		return true
+	}
+	ptrType := reflect.PtrTo(valType)
+	return valType.Implements(redisMarshalerType) || ptrType.Implements(redisMarshalerType) ||
+		ptrType.Implements(redisUnmarshalerType)
+}
@@ -0,0 +1,147 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// kdecimal stands for a third-party type with unexported fields.
+type kdecimal struct {
+	value int64
+	exp   int32
+}
+
+func (d kdecimal) String() string {
+	return fmt.Sprintf("%de%d", d.value, d.exp)
+}
+
+var kdecimalConverter = Converter{
+	Marshal: func(v interface{}) ([]byte, error) {
+		return []byte(v.(kdecimal).String()), nil
+	},
+	Unmarshal: func(data []byte, v interface{}) error {
+		parts := strings.Split(string(data), "e")
+		if len(parts) != 2 {
+			return errors.New("kdecimal: invalid format")
+		}
+		value, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		exp, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil {
+			return err
+		}
+		*v.(*kdecimal) = kdecimal{value: value, exp: int32(exp)}
+		return nil
+	},
+}
+
+// kredis is written as "x:y" whatever the codec.
+type kredis struct {
+	X int
+	Y int
+}
+
+func (k kredis) MarshalRedis() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%d", k.X, k.Y)), nil
+}
+
+func (k *kredis) UnmarshalRedis(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "%d:%d", &k.X, &k.Y)
+	return err
+}
+
+type converterstruct struct {
+	Kdecimal  kdecimal  `json:"kdecimal"`
+	Kpdecimal *kdecimal `json:"kpdecimal"`
+	Kredis    kredis    `json:"kredis,flatten"`
+	// elements of collections are encoded by the codec
+	Kredises []kredis `json:"kredises"`
+}
+
+func TestClient_RegisterConverter(t *testing.T) {
+	c := NewRedisClient(client.Cmdable, Flatten())
+	c.RegisterConverter(kdecimal{}, kdecimalConverter)
+
+	in := converterstruct{
+		Kdecimal:  kdecimal{value: 12345, exp: -2},
+		Kpdecimal: &kdecimal{value: 1, exp: 3},
+		Kredis:    kredis{X: 1, Y: 2},
+		Kredises:  []kredis{{X: 3, Y: 4}},
+	}
+	err := c.SetStructValue(context.Background(), "kconverterstruct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.HGetAll(context.Background(), "kconverterstruct").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"kdecimal":  "12345e-2",
+		"kpdecimal": "1e3",
+		"kredis":    "1:2",
+		"kredises":  `[{"X":3,"Y":4}]`,
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+
+	var out converterstruct
+	err = c.GetStructValue(context.Background(), "kconverterstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	// converters are registered per client
+	if _, ok := client.options.converters.lookup(reflect.TypeOf(kdecimal{})); ok {
+		t.Error("converter should not be registered on other clients")
+	}
+}
+
+func TestClient_SetValueRedisMarshaler(t *testing.T) {
+	c := NewRedisClient(client.Cmdable)
+	c.RegisterConverter(kdecimal{}, kdecimalConverter)
+
+	err := c.SetValue(context.Background(), "kredis", kredis{X: 5, Y: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetValue(context.Background(), "kdecimal", kdecimal{value: 7, exp: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	str, err := c.Get(context.Background(), "kredis").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str != "5:6" {
+		t.Errorf("got %q, want %q", str, "5:6")
+	}
+
+	var kr kredis
+	err = c.GetValue(context.Background(), "kredis", &kr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kr != (kredis{X: 5, Y: 6}) {
+		t.Errorf("got %+v", kr)
+	}
+	var kd kdecimal
+	err = c.GetValue(context.Background(), "kdecimal", &kd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kd != (kdecimal{value: 7, exp: 1}) {
+		t.Errorf("got %+v", kd)
+	}
+}
@@ -0,0 +1,310 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var errElementCond = errors.New("redis: element hashes can't be written conditionally")
+
+// elementStructType returns the struct type of the elements of a slice stored as element hashes.
+func elementStructType(valValue reflect.Value, options *Options) (reflect.Type, error) {
+	structType := valValue.Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct || structType == timeType || isRedisScalar(structType, options) {
+		return nil, unsupportedTypeError(reflect.New(valValue.Type().Elem()).Elem(), "slice of structs")
+	}
+	return structType, nil
+}
+
+// elementKey returns the key of the hash of the element i of the slice stored at key.
+func elementKey(key string, i int, elem reflect.Value, plan *structPlan, options *Options) (string, error) {
+	pk := strconv.Itoa(i)
+	if field, ok := plan.fieldBy(isPK); ok {
+		pkValue, ok := fieldByIndex(elem, field.index)
+		if !ok || isNilValue(pkValue) {
+			return "", fmt.Errorf("redis: element %d has no %s", i, field.key)
+		}
+		b, err := toByte(pkValue, options)
+		if err != nil {
+			return "", err
+		}
+		if len(b) == 0 {
+			return "", fmt.Errorf("redis: element %d has an empty %s", i, field.key)
+		}
+		pk = bytesToString(b)
+	}
+	if options.ElementKey == "" {
+		return key + ":" + pk, nil
+	}
+	return strings.NewReplacer("{key}", key, "{pk}", pk, "{index}", strconv.Itoa(i)).Replace(options.ElementKey), nil
+}
+
+// setElementHashes writes each struct element as a hash and the keys of the hashes at key,
+// all in one transaction. Nil elements are skipped. Lists get the keys that aren't members yet,
+// in Replace mode the hashes and companion keys of the members left out are deleted.
+func (c *Client) setElementHashes(ctx context.Context, key string, valValue reflect.Value, options Options) (res SetResult, err error) {
+	if options.conditional() {
+		return res, errElementCond
+	}
+	structType, err := elementStructType(valValue, &options)
+	if err != nil {
+		return res, err
+	}
+	plan := cachedStructPlan(structType, &options)
+	elemPlan, err := plan.project(&options)
+	if err != nil {
+		return res, err
+	}
+	elemOptions := options
+	if options.partial() {
+		// the fields left out are kept
+		elemOptions.WriteMode = Merge
+	}
+	scoreBy, _ := scoreField(structType, &options)
+	kind := kindList
+	switch options.SliceType {
+	case Set:
+		kind = kindSet
+	case ZSet:
+		kind = kindZSet
+	}
+
+	valLen := valValue.Len()
+	elemKeys := make([]string, 0, valLen)
+	writes := make([]keyWrite, 0, valLen)
+	var scores []interface{}
+	var companionKeys [][]string
+	var companions [][]keyWrite
+	for i := 0; i < valLen; i++ {
+		elem := valValue.Index(i)
+		if isNilValue(elem) {
+			continue
+		}
+		elemKey, err := elementKey(key, i, reflect.Indirect(elem), plan, &options)
+		if err != nil {
+			return res, err
+		}
+		w, err := structWrite(reflect.Indirect(elem), elemPlan, &elemOptions)
+		if err != nil {
+			return res, err
+		}
+		if len(elemPlan.companions) > 0 {
+			keys, elemCompanions, err := companionWrites(elemKey, reflect.Indirect(elem), elemPlan, &options)
+			if err != nil {
+				return res, err
+			}
+			companionKeys = append(companionKeys, keys)
+			companions = append(companions, elemCompanions)
+		}
+		if kind == kindZSet {
+			score, err := elementScore(elem, i, scoreBy)
+			if err != nil {
+				return res, err
+			}
+			scores = append(scores, score)
+		}
+		elemKeys = append(elemKeys, elemKey)
+		writes = append(writes, w)
+	}
+	if len(elemKeys) == 0 && options.WriteMode != Replace {
+		return res, nil
+	}
+
+	readMembers := options.WriteMode == Replace || kind == kindList
+	err = c.withMembers(ctx, key, kind, readMembers, func(oldKeys []string, tx txFunc) error {
+		isMember := make(map[string]bool, len(oldKeys))
+		if options.WriteMode != Replace {
+			for _, oldKey := range oldKeys {
+				isMember[oldKey] = true
+			}
+		}
+		members := keyWrite{kind: kind}
+		for i, elemKey := range elemKeys {
+			if kind == kindList && isMember[elemKey] {
+				continue
+			}
+			isMember[elemKey] = true
+			if kind == kindZSet {
+				members.args = append(members.args, scores[i])
+			}
+			members.args = append(members.args, elemKey)
+		}
+		var staleKeys []string
+		for _, oldKey := range oldKeys {
+			if options.WriteMode == Replace && !isMember[oldKey] {
+				staleKeys = append(staleKeys, oldKey)
+				for i := range plan.companions {
+					staleKeys = append(staleKeys, companionKey(oldKey, &plan.companions[i]))
+				}
+			}
+		}
+
+		return tx(ctx, func(pipe redis.Pipeliner) error {
+			if len(staleKeys) > 0 {
+				pipe.Del(ctx, staleKeys...)
+			}
+			for i, elemKey := range elemKeys {
+				writes[i].queueKey(ctx, pipe, elemKey, &elemOptions)
+				if len(elemPlan.companions) > 0 {
+					queueCompanions(ctx, pipe, companionKeys[i], companions[i], &options)
+				}
+			}
+			if options.WriteMode == Replace {
+				pipe.Del(ctx, key)
+			}
+			if len(elemKeys) > 0 {
+				members.queue(ctx, pipe, key)
+				if options.Expiration > 0 {
+					pipe.Expire(ctx, key, options.Expiration)
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return res, err
+	}
+	res.Written = true
+	return res, nil
+}
+
+// txFunc queues commands in a MULTI/EXEC transaction.
+type txFunc func(ctx context.Context, fn func(pipe redis.Pipeliner) error) error
+
+// watcher is implemented by the clients that can WATCH keys.
+type watcher interface {
+	Watch(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error
+}
+
+// watchRetries is how many times withMembers reads the members again
+// when they change before the transaction.
+const watchRetries = 5
+
+// withMembers calls fn with the keys of the element hashes held at key, when read is set,
+// and the transaction of the writes. With the clients that can WATCH key, the transaction fails
+// when the members change after the read and fn is called again, the others read outside of it.
+func (c *Client) withMembers(ctx context.Context, key string, kind string, read bool, fn func(oldKeys []string, tx txFunc) error) error {
+	w, ok := c.Cmdable.(watcher)
+	if !ok || !read {
+		var oldKeys []string
+		if read {
+			if _, ok := c.Cmdable.(redis.Pipeliner); ok {
+				return errPipelinedRead
+			}
+			var err error
+			if oldKeys, err = memberKeys(ctx, c.Cmdable, key, kind); err != nil {
+				return err
+			}
+		}
+		return fn(oldKeys, c.txPipelined)
+	}
+	for i := 0; i < watchRetries; i++ {
+		err := w.Watch(ctx, func(t *redis.Tx) error {
+			oldKeys, err := memberKeys(ctx, t, key, kind)
+			if err != nil {
+				return err
+			}
+			return fn(oldKeys, func(ctx context.Context, queue func(pipe redis.Pipeliner) error) error {
+				_, err := t.TxPipelined(ctx, queue)
+				return err
+			})
+		}, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return redis.TxFailedErr
+}
+
+// memberKeys returns the keys of the element hashes held at key.
+func memberKeys(ctx context.Context, cmd redis.Cmdable, key string, kind string) ([]string, error) {
+	switch kind {
+	case kindSet:
+		return cmd.SMembers(ctx, key).Result()
+	case kindZSet:
+		return cmd.ZRange(ctx, key, 0, -1).Result()
+	}
+	return cmd.LRange(ctx, key, 0, -1).Result()
+}
+
+// getElementHashes reads the keys of the element hashes at key, with the readers of
+// SliceType, then the hashes in one pipeline. Elements keep the order of their keys,
+// keys whose hash is gone are skipped.
+func (c *Client) getElementHashes(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
+	structType, err := elementStructType(valValue, &options)
+	if err != nil {
+		return err
+	}
+	var elemKeys []string
+	keysOptions := options
+	keysOptions.WithScores = false
+	if valValue.Kind() == reflect.Array {
+		if keysOptions.Stop == 0 {
+			keysOptions.Stop = keysOptions.Start + int64(valValue.Len()) - 1
+		}
+	}
+	if err = c.getSliceByType(ctx, key, reflect.ValueOf(&elemKeys).Elem(), keysOptions); err != nil {
+		return err
+	}
+	if valValue.Kind() == reflect.Array && len(elemKeys) > valValue.Len() {
+		elemKeys = elemKeys[:valValue.Len()]
+	}
+
+	plan, err := cachedStructPlan(structType, &options).project(&options)
+	if err != nil {
+		return err
+	}
+	cmds := make([]*redis.StringStringMapCmd, len(elemKeys))
+	companionCmds := make([][]redis.Cmder, len(elemKeys))
+	err = c.pipelined(ctx, func(pipe redis.Cmdable) {
+		for i, elemKey := range elemKeys {
+			cmds[i] = pipe.HGetAll(ctx, elemKey)
+			companionCmds[i] = readCompanions(ctx, pipe, elemKey, plan)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	migrations := options.migrations.lookup(structType)
+	var errs DecodeErrors
+	elems := reflect.MakeSlice(reflect.SliceOf(valValue.Type().Elem()), 0, len(cmds))
+	for i, cmd := range cmds {
+		elem := reflect.New(structType).Elem()
+		found, elemErrs := decodeCompanions(elem, plan, companionCmds[i], &options)
+		hash := cmd.Val()
+		if len(hash) == 0 && !found {
+			continue
+		}
+		err = nil
+		if len(hash) > 0 && len(migrations) > 0 {
+			_, err = migrate(hash, migrations, schemaVersion(elem, &options))
+		}
+		if err == nil {
+			err = plan.decodeHash(elem, hash, &options)
+			applyDefaults(elem)
+		}
+		if elemErrs = appendErrors(elemErrs, err); len(elemErrs) > 0 {
+			errs = append(errs, entryErrors(elemErrs, elemKeys[i])...)
+		}
+		if valValue.Type().Elem().Kind() == reflect.Ptr {
+			elem = elem.Addr()
+		}
+		elems = reflect.Append(elems, elem)
+	}
+	if valValue.Kind() == reflect.Array {
+		reflect.Copy(valValue, elems)
+	} else {
+		valValue.Set(elems)
+	}
+	return decodeError(errs.err(), key)
+}
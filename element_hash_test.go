@@ -0,0 +1,185 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type elementuser struct {
+	ID   string  `json:"id,pk"`
+	Name string  `json:"name"`
+	Rank float64 `json:"rank,score"`
+}
+
+func TestClient_ElementHashes(t *testing.T) {
+	ctx := context.Background()
+	key := "kelements"
+	defer client.Del(ctx, key, key+":u2", key+":u1", key+":u3")
+
+	in := []elementuser{{"u2", "bob", 2}, {"u1", "alice", 1}, {"u3", "carol", 3}}
+	err := client.SetSliceValue(ctx, key, in, ElementHashes(""), UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(members, []string{key + ":u2", key + ":u1", key + ":u3"}) {
+		t.Errorf("got members %v", members)
+	}
+	if name, _ := client.HGet(ctx, key+":u1", "name").Result(); name != "alice" {
+		t.Errorf("got name %q", name)
+	}
+
+	var out []*elementuser
+	if err = client.GetSliceValue(ctx, key, &out, ElementHashes("")); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 3 || *out[0] != in[0] || *out[1] != in[1] || *out[2] != in[2] {
+		t.Errorf("got %v", out)
+	}
+
+	// a dangling member is skipped
+	client.Del(ctx, key+":u1")
+	var arr [3]elementuser
+	if err = client.GetSliceValue(ctx, key, &arr, ElementHashes("")); err != nil {
+		t.Fatal(err)
+	}
+	if arr[0] != in[0] || arr[1] != in[2] || arr[2] != (elementuser{}) {
+		t.Errorf("got %v", arr)
+	}
+}
+
+func TestClient_ElementHashesZSet(t *testing.T) {
+	ctx := context.Background()
+	key := "kelementszset"
+	defer client.Del(ctx, key, "user:u1", "user:u2", "user:u3")
+
+	in := []elementuser{{"u2", "bob", 2}, {"u1", "alice", 1}, {"u3", "carol", 3}}
+	err := client.SetSliceValue(ctx, key, in, ElementHashes("user:{pk}"), RedisTypeZSet(), UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []elementuser
+	err = client.GetSliceValue(ctx, key, &out, ElementHashes("user:{pk}"), RedisTypeZSet(), ScoreRange("2", "+inf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, []elementuser{in[0], in[2]}) {
+		t.Errorf("got %v", out)
+	}
+}
+
+func TestClient_ElementHashesErrors(t *testing.T) {
+	ctx := context.Background()
+	key := "kelementserr"
+
+	var out []elementuser
+	if err := client.GetSliceValue(ctx, key, &out, ElementHashes("")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v", err)
+	}
+	err := client.SetSliceValue(ctx, key, []elementuser{{Name: "nobody"}}, ElementHashes(""))
+	if err == nil {
+		t.Error("want an error for an empty pk")
+	}
+	err = client.SetSliceValue(ctx, key, []string{"a"}, ElementHashes(""))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v", err)
+	}
+	err = client.SetSliceValue(ctx, key, []elementuser{{ID: "u1"}}, ElementHashes(""), IfAbsent())
+	if !errors.Is(err, errElementCond) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestClient_ElementHashesRewrite(t *testing.T) {
+	ctx := context.Background()
+	key := "kelementsrewrite"
+	defer client.Del(ctx, key, key+":e1", key+":e1:tags", key+":e2", key+":e2:tags", key+":e3")
+
+	in := []companionelement{{"e1", []string{"t"}}, {"e2", []string{"u"}}}
+	for i := 0; i < 2; i++ {
+		if err := client.SetSliceValue(ctx, key, in, ElementHashes("")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out []companionelement
+	if err := client.GetSliceValue(ctx, key, &out, ElementHashes("")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v", out)
+	}
+
+	// members left out are deleted with their companion keys
+	in = []companionelement{{"e3", nil}, {"e1", []string{"v"}}}
+	err := client.SetSliceValue(ctx, key, in, ElementHashes(""), UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.Exists(ctx, key+":e2", key+":e2:tags").Result(); n != 0 {
+		t.Errorf("%d stale keys left", n)
+	}
+	out = nil
+	if err = client.GetSliceValue(ctx, key, &out, ElementHashes("")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v", out)
+	}
+}
+
+// memberhook adds a member with another client after the first read of the members.
+type memberhook struct {
+	key, member string
+	done        bool
+}
+
+func (h *memberhook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *memberhook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if cmd.Name() == "lrange" && !h.done {
+		h.done = true
+		client.HSet(ctx, h.member, "id", "x")
+		client.RPush(ctx, h.key, h.member)
+	}
+	return nil
+}
+
+func (h *memberhook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *memberhook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestClient_ElementHashesConcurrent(t *testing.T) {
+	ctx := context.Background()
+	key := "kelementsconcurrent"
+	defer client.Del(ctx, key, key+":e1", key+":x")
+
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer rdb.Close()
+	rdb.AddHook(&memberhook{key: key, member: key + ":x"})
+	c := NewRedisClient(rdb)
+
+	// the member added after the read is seen on the retry and deleted
+	err := c.SetSliceValue(ctx, key, []elementuser{{ID: "e1", Name: "a"}}, ElementHashes(""), UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := client.LRange(ctx, key, 0, -1).Result(); !reflect.DeepEqual(members, []string{key + ":e1"}) {
+		t.Errorf("got members %v", members)
+	}
+	if n, _ := client.Exists(ctx, key+":x").Result(); n != 0 {
+		t.Error("the concurrent member is left")
+	}
+}
@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrNotFound is returned by the getters when the key does not exist,
+	// errors.Is(ErrNotFound, redis.Nil) also holds.
+	ErrNotFound error = notFoundError{}
+	// ErrUnsupportedType is returned for values of a type that can't be stored or read by the method.
+	ErrUnsupportedType = errors.New("redis: unsupported type")
+	// ErrNotPointer is returned by the getters when value isn't a non-nil pointer.
+	ErrNotPointer = errors.New("redis: value is not a non-nil pointer")
+	// ErrUnknownField is the cause of the DecodeError of a hash field without struct field in strict mode.
+	ErrUnknownField = errors.New("redis: unknown field")
+	// ErrMissingField is the cause of the DecodeError of a missing required field in strict mode.
+	ErrMissingField = errors.New("redis: missing required field")
+)
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "redis: key not found" }
+
+func (notFoundError) Is(target error) bool { return target == redis.Nil }
+
+// DecodeError is returned when a value read from redis can't be decoded,
+// Field is the hash field, or the list index, or empty for strings.
+type DecodeError struct {
+	Key   string
+	Field string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("redis: decode %q: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("redis: decode %q field %q: %v", e.Key, e.Field, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// DecodeErrors collects the failures of the fields of a struct, the other fields are still decoded.
+// errors.Is and errors.As look at every failure, in order.
+type DecodeErrors []*DecodeError
+
+func (e DecodeErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e DecodeErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e DecodeErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// err returns nil when there is no failure.
+func (e DecodeErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// unsupportedTypeError wraps ErrUnsupportedType with the type of value.
+func unsupportedTypeError(value reflect.Value, want string) error {
+	if !value.IsValid() {
+		return fmt.Errorf("%w: nil, want %s", ErrUnsupportedType, want)
+	}
+	return fmt.Errorf("%w: %s, want %s", ErrUnsupportedType, value.Type(), want)
+}
+
+// notFound maps redis.Nil to ErrNotFound.
+func notFound(err error) error {
+	if err == redis.Nil {
+		return ErrNotFound
+	}
+	return err
+}
+
+// decodeError sets the key of a *DecodeError, the other errors are wrapped in one.
+func decodeError(err error, key string) error {
+	if err == nil {
+		return nil
+	}
+	switch e := err.(type) {
+	case *DecodeError:
+		e.Key = key
+		return e
+	case DecodeErrors:
+		for _, fieldErr := range e {
+			fieldErr.Key = key
+		}
+		return e
+	}
+	return &DecodeError{Key: key, Err: err}
+}
+
+// appendErrors appends the decode errors of err to errs, other errors have no field.
+func appendErrors(errs DecodeErrors, err error) DecodeErrors {
+	switch e := err.(type) {
+	case nil:
+		return errs
+	case *DecodeError:
+		return append(errs, e)
+	case DecodeErrors:
+		return append(errs, e...)
+	}
+	return append(errs, &DecodeError{Err: err})
+}
@@ -0,0 +1,186 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestClient_ErrNotFound(t *testing.T) {
+	ctx := context.Background()
+	key := "knotfound"
+	client.Del(ctx, key)
+
+	var s kstruct
+	var m map[string]int
+	var slice []string
+	var array [2]string
+	var str string
+	for name, get := range map[string]func() error{
+		"GetStructValue": func() error { return client.GetStructValue(ctx, key, &s) },
+		"GetMapValue":    func() error { return client.GetMapValue(ctx, key, &m) },
+		"GetSliceValue":  func() error { return client.GetSliceValue(ctx, key, &slice) },
+		"GetArrayValue":  func() error { return client.GetSliceValue(ctx, key, &array) },
+		"GetValue":       func() error { return client.GetValue(ctx, key, &str) },
+	} {
+		err := get()
+		if !errors.Is(err, ErrNotFound) || !errors.Is(err, redis.Nil) {
+			t.Errorf("%s: got %v, want ErrNotFound", name, err)
+		}
+	}
+
+	// a hash without any of the fields exists
+	client.HSet(ctx, key, "other", "v")
+	defer client.Del(ctx, key)
+	if err := client.GetStructValue(ctx, key, &s); err != nil {
+		t.Errorf("got %v", err)
+	}
+	// so does a list read out of range
+	client.Del(ctx, key)
+	client.RPush(ctx, key, "a")
+	if err := client.GetSliceValue(ctx, key, &slice, Range(5, 6)); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestClient_ErrTypes(t *testing.T) {
+	ctx := context.Background()
+	var s kstruct
+	if err := client.GetStructValue(ctx, "kerrtypes", s); !errors.Is(err, ErrNotPointer) {
+		t.Errorf("got %v, want ErrNotPointer", err)
+	}
+	if err := client.GetValue(ctx, "kerrtypes", (*kstruct)(nil)); !errors.Is(err, ErrNotPointer) {
+		t.Errorf("got %v, want ErrNotPointer", err)
+	}
+	if err := client.GetSliceValue(ctx, "kerrtypes", []string{}); !errors.Is(err, ErrNotPointer) {
+		t.Errorf("got %v, want ErrNotPointer", err)
+	}
+	var str string
+	if err := client.GetStructValue(ctx, "kerrtypes", &str); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+	if err := client.SetMapValue(ctx, "kerrtypes", s); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+	if err := client.SetValue(ctx, "kerrtypes", make(chan int)); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+	// nil values have no type to write
+	for _, value := range []interface{}{nil, (*kstruct)(nil), (*string)(nil)} {
+		if err := client.SetValue(ctx, "kerrtypes", value); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("SetValue(%T) got %v, want ErrUnsupportedType", value, err)
+		}
+		if err := client.SetSingleValue(ctx, "kerrtypes", value); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("SetSingleValue(%T) got %v, want ErrUnsupportedType", value, err)
+		}
+	}
+}
+
+func TestClient_DecodeError(t *testing.T) {
+	ctx := context.Background()
+	key := "kdecodeerror"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.HSet(ctx, key, "k", "v", "k_1", "NaN")
+
+	var s kstruct
+	err := client.GetStructValue(ctx, key, &s)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("got %v, want *DecodeError", err)
+	}
+	if decodeErr.Key != key || decodeErr.Field != "k_1" || decodeErr.Err == nil {
+		t.Errorf("got %+v", decodeErr)
+	}
+
+	var m map[string]int
+	err = client.GetMapValue(ctx, key, &m)
+	if !errors.As(err, &decodeErr) || decodeErr.Key != key {
+		t.Errorf("got %v, want *DecodeError", err)
+	}
+
+	client.Set(ctx, key, "NaN", 0)
+	var n int
+	err = client.GetValue(ctx, key, &n)
+	if !errors.As(err, &decodeErr) || decodeErr.Key != key || decodeErr.Field != "" {
+		t.Errorf("got %v, want *DecodeError", err)
+	}
+}
+
+type strictstruct struct {
+	K     string `json:"k,required"`
+	K1    int    `json:"k_1"`
+	K2    int    `json:"k_2"`
+	Kname string `json:"kname,required"`
+}
+
+func TestClient_StrictDecode(t *testing.T) {
+	ctx := context.Background()
+	key := "kstrictstruct"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.HSet(ctx, key, "k_1", "NaN", "k_2", "2", "kname", "v", "kother", "v", "kaother", "v")
+
+	// lenient mode populates the good fields and reports all the bad ones
+	var out strictstruct
+	err := client.GetStructValue(ctx, key, &out)
+	var errs DecodeErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "k_1" {
+		t.Fatalf("got %v", err)
+	}
+	if out.K2 != 2 || out.Kname != "v" {
+		t.Errorf("got %+v", out)
+	}
+
+	out = strictstruct{}
+	err = client.GetStructValue(ctx, key, &out, Strict())
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want DecodeErrors", err)
+	}
+	want := []struct {
+		field string
+		err   error
+	}{
+		{"k", ErrMissingField},
+		{"k_1", nil},
+		{"kaother", ErrUnknownField},
+		{"kother", ErrUnknownField},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %v", errs)
+	}
+	for i, w := range want {
+		if errs[i].Key != key || errs[i].Field != w.field || w.err != nil && !errors.Is(errs[i], w.err) {
+			t.Errorf("%d: got %v, want %s %v", i, errs[i], w.field, w.err)
+		}
+	}
+	if out.K2 != 2 || out.Kname != "v" {
+		t.Errorf("got %+v", out)
+	}
+	// every failure is matched, not just the first one
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) || numErr.Num != "NaN" {
+		t.Errorf("errors.As should see the k_1 failure, got %v", err)
+	}
+	for _, target := range []error{ErrMissingField, ErrUnknownField, strconv.ErrSyntax} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v) should hold, got %v", target, err)
+		}
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("errors.Is(ErrNotFound) should not hold, got %v", err)
+	}
+
+	client.HSet(ctx, key, "k", "v", "k_1", "1")
+	client.HDel(ctx, key, "kother", "kaother")
+	if err = client.GetStructValue(ctx, key, &out, Strict()); err != nil {
+		t.Errorf("got %v", err)
+	}
+	client.Del(ctx, key)
+	if err = client.GetStructValue(ctx, key, &out, Strict()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
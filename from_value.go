@@ -1,52 +1,103 @@
 package redis
 
 import (
-	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 )
 
-func toString(value reflect.Value) (bs string) {
-	if value.Kind() == reflect.Ptr {
+func toString(value reflect.Value, options *Options) (bs string, err error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return "", nil
+		}
 		value = value.Elem()
 	}
 	switch value.Kind() {
 	case reflect.Bool:
-		return strconv.FormatBool(value.Bool())
+		return strconv.FormatBool(value.Bool()), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return strconv.FormatInt(value.Int(), 10)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.FormatUint(value.Uint(), 10)
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
 	case reflect.Float32, reflect.Float64:
-		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
 	case reflect.Complex64, reflect.Complex128:
-		return strconv.FormatComplex(value.Complex(), 'f', -1, 128)
+		return strconv.FormatComplex(value.Complex(), 'f', -1, 128), nil
 	case reflect.String:
-		return value.String()
+		return value.String(), nil
 	default:
-		bytes := toByte(value)
+		bytes, err := toByte(value, options)
+		if err != nil {
+			return "", err
+		}
 		if bytes != nil {
-			return bytesToString(bytes)
+			return bytesToString(bytes), nil
 		}
-		return ""
+		return "", nil
 	}
 }
 
-func toByte(value reflect.Value) (bs []byte) {
-	v := value.Interface()
-	if ok, bytes := dotType2Byte(v); ok {
-		return bytes
+func toByte(value reflect.Value, options *Options) (bs []byte, err error) {
+	if !value.IsValid() {
+		return nil, unsupportedTypeError(value, "value")
 	}
-	if value.Kind() == reflect.Ptr {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		// nil is written as an empty value
+		if value.IsNil() {
+			return nil, nil
+		}
 		value = value.Elem()
 	}
+	if converter, ok := options.converters.lookup(value.Type()); ok && converter.Marshal != nil {
+		return converter.Marshal(value.Interface())
+	}
+	if ok, bytes, err := marshalValue(value, options); ok {
+		return bytes, err
+	}
 	switch value.Kind() {
-	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
-		bytes, _ := json.Marshal(v)
-		return bytes
-	case reflect.Interface:
-		return toByte(reflect.ValueOf(v))
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			// byte slices are written as is
+			return value.Bytes(), nil
+		}
+		return options.Codec.Marshal(value.Interface())
+	case reflect.Map, reflect.Struct, reflect.Array:
+		return options.Codec.Marshal(value.Interface())
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		s, err := toString(value, options)
+		if err != nil {
+			return nil, err
+		}
+		return stringToBytes(s), nil
 	default:
-		return stringToBytes(toString(value))
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, value.Type())
 	}
 }
+
+// marshalValue is dotType2Byte also considering the marshalers of *T,
+// so that values are written the way setValueByString reads them.
+func marshalValue(value reflect.Value, options *Options) (ok bool, bs []byte, err error) {
+	if ok, bs, err = dotType2Byte(value.Interface(), options); ok {
+		return ok, bs, err
+	}
+	if value.Kind() == reflect.Ptr || !implementsMarshaler(reflect.PtrTo(value.Type())) {
+		return false, nil, nil
+	}
+	if !value.CanAddr() {
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		value = ptr.Elem()
+	}
+	return dotType2Byte(value.Addr().Interface(), options)
+}
+
+func implementsMarshaler(valType reflect.Type) bool {
+	return valType.Implements(redisMarshalerType) ||
+		valType.Implements(binaryMarshalerType) ||
+		valType.Implements(textMarshalerType) ||
+		valType.Implements(jsonMarshalerType)
+}
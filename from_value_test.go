@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type complexstruct struct {
+	Kcomplex64   complex64   `json:"kcomplex64"`
+	Kcomplex128  complex128  `json:"kcomplex128"`
+	Kpcomplex    *complex128 `json:"kpcomplex"`
+	Kscomplex    complex64   `json:"kscomplex,string"`
+	Kuintptr     uintptr     `json:"kuintptr"`
+	Kbytes       []byte      `json:"kbytes"`
+	Kcomplexnone complex128  `json:"kcomplexnone"`
+}
+
+type kbytes []byte
+
+func TestRoundTrip_Kinds(t *testing.T) {
+	c := complex(1.5, -2)
+	tm := time.Date(2021, 8, 1, 12, 30, 45, 123456789, time.UTC)
+	tests := []struct {
+		name string
+		in   interface{}
+		want string // the encoded value, unchecked when empty
+	}{
+		{"bool", true, "true"},
+		{"int", int(-1), "-1"},
+		{"int8", int8(-8), "-8"},
+		{"int16", int16(-16), "-16"},
+		{"int32", int32(-32), "-32"},
+		{"int64", int64(-64), "-64"},
+		{"uint", uint(1), "1"},
+		{"uint8", uint8(8), "8"},
+		{"uint16", uint16(16), "16"},
+		{"uint32", uint32(32), "32"},
+		{"uint64", uint64(64), "64"},
+		{"uintptr", uintptr(0xff), "255"},
+		{"float32", float32(0.5), "0.5"},
+		{"float64", 1.25, "1.25"},
+		{"complex64", complex64(complex(1, 2)), "(1+2i)"},
+		{"complex128", complex(0.5, -1.5), "(0.5-1.5i)"},
+		{"string", "v", "v"},
+		{"bytes", []byte("v\x00"), "v\x00"},
+		{"named bytes", kbytes("v"), "v"},
+		{"array", [2]int{1, 2}, "[1,2]"},
+		{"slice", []string{"a", "b"}, `["a","b"]`},
+		{"map", map[string]int{"a": 1}, `{"a":1}`},
+		{"struct", subkstruct{K: "v", Ktime: tm}, ""},
+		{"pointer", &c, "(1.5-2i)"},
+		{"time", tm, "2021-08-01T12:30:45.123456789Z"},
+		{"duration", time.Second, "1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs, err := toByte(reflect.ValueOf(tt.in), &client.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != "" && string(bs) != tt.want {
+				t.Errorf("toByte() = %q, want %q", bs, tt.want)
+			}
+			out := reflect.New(reflect.TypeOf(tt.in))
+			err = setValueByString(out.Elem(), string(bs), &client.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(out.Elem().Interface(), tt.in) {
+				t.Errorf("got %v, want %v", out.Elem().Interface(), tt.in)
+			}
+		})
+	}
+}
+
+func TestToByte_Unsupported(t *testing.T) {
+	for _, in := range []interface{}{make(chan int), func() {}} {
+		if _, err := toByte(reflect.ValueOf(in), &client.options); err == nil {
+			t.Errorf("toByte(%T) should fail", in)
+		}
+	}
+}
+
+func TestSetValueByString_Complex(t *testing.T) {
+	var c128 complex128
+	for val, want := range map[string]complex128{
+		"(1+2i)": complex(1, 2),
+		"3i":     3i,
+		"-1.5":   -1.5,
+		"":       0,
+	} {
+		err := setValueByString(reflect.ValueOf(&c128).Elem(), val, &client.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c128 != want {
+			t.Errorf("%q: got %v, want %v", val, c128, want)
+		}
+	}
+	var c64 complex64
+	if err := setValueByString(reflect.ValueOf(&c64).Elem(), "(1e40+0i)", &client.options); err == nil {
+		t.Error("complex64 overflow should fail")
+	}
+}
+
+func TestClient_ComplexValues(t *testing.T) {
+	ctx := context.Background()
+	c := complex(3, 4)
+	in := complexstruct{
+		Kcomplex64:  complex(1, -1),
+		Kcomplex128: complex(0.1, 0.2),
+		Kpcomplex:   &c,
+		Kscomplex:   2i,
+		Kuintptr:    42,
+		Kbytes:      []byte("raw"),
+	}
+	err := client.SetStructValue(ctx, "kcomplexstruct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := client.HGetAll(ctx, "kcomplexstruct").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["kcomplex64"] != "(1-1i)" || m["kscomplex"] != "(0+2i)" || m["kbytes"] != "raw" {
+		t.Errorf("got %v", m)
+	}
+	var out complexstruct
+	err = client.GetStructValue(ctx, "kcomplexstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	err = client.SetValue(ctx, "kcomplex", complex64(complex(1.5, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kc complex64
+	err = client.GetValue(ctx, "kcomplex", &kc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kc != complex(1.5, 2) {
+		t.Errorf("got %v", kc)
+	}
+
+	slice := []complex128{complex(1, 2), -3i, 0.25}
+	err = client.SetSliceValue(ctx, "kcomplexslice", slice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outSlice []complex128
+	err = client.GetSliceValue(ctx, "kcomplexslice", &outSlice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(slice, outSlice) {
+		t.Errorf("got %v, want %v", outSlice, slice)
+	}
+
+	array := [2]complex64{complex(1, 1), 2}
+	err = client.SetValue(ctx, "kcomplexarray", array)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outArray [2]complex64
+	err = client.GetValue(ctx, "kcomplexarray", &outArray)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outArray != array {
+		t.Errorf("got %v, want %v", outArray, array)
+	}
+}
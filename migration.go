@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// versionField is the hidden hash field holding the schema version of a struct.
+const versionField = "_version"
+
+// SchemaVersioner is implemented by structs that carry a schema version,
+// it's written to the hidden "_version" hash field and takes precedence over Options.SchemaVersion.
+type SchemaVersioner interface {
+	SchemaVersion() int
+}
+
+var schemaVersionerType = reflect.TypeOf((*SchemaVersioner)(nil)).Elem()
+
+// Migration upgrades the raw hash of a struct by one schema version, in place.
+type Migration func(hash map[string]string) error
+
+type migrationRegistry struct {
+	mu         sync.RWMutex
+	migrations map[reflect.Type]map[int]Migration
+}
+
+func newMigrationRegistry() *migrationRegistry {
+	return &migrationRegistry{migrations: map[reflect.Type]map[int]Migration{}}
+}
+
+// lookup returns a copy of the migrations of a type by the version they upgrade from,
+// safe to use while more are registered.
+func (r *migrationRegistry) lookup(valType reflect.Type) map[int]Migration {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	migrations := r.migrations[valType]
+	if len(migrations) == 0 {
+		return nil
+	}
+	copied := make(map[int]Migration, len(migrations))
+	for from, migration := range migrations {
+		copied[from] = migration
+	}
+	return copied
+}
+
+// RegisterMigration registers the migration of the hashes of the struct type of value
+// from version from to from+1. Hashes written without version are at version 0.
+func (c *Client) RegisterMigration(value interface{}, from int, migration Migration) {
+	valType := reflect.TypeOf(value)
+	if valType.Kind() == reflect.Ptr {
+		valType = valType.Elem()
+	}
+	r := c.options.migrations
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.migrations[valType] == nil {
+		r.migrations[valType] = map[int]Migration{}
+	}
+	r.migrations[valType][from] = migration
+}
+
+// schemaVersion returns the version written with a struct, 0 for none.
+func schemaVersion(valValue reflect.Value, options *Options) int {
+	if !valValue.CanAddr() && reflect.PtrTo(valValue.Type()).Implements(schemaVersionerType) {
+		// copy the struct passed by value to find the pointer methods
+		ptr := reflect.New(valValue.Type())
+		ptr.Elem().Set(valValue)
+		valValue = ptr.Elem()
+	}
+	if valValue.CanAddr() {
+		valValue = valValue.Addr()
+	}
+	if versioner, ok := valValue.Interface().(SchemaVersioner); ok {
+		return versioner.SchemaVersion()
+	}
+	return options.SchemaVersion
+}
+
+// migrate runs the chain of migrations from the version of the hash up to the
+// target version, or as far as there are migrations when there is no target.
+func migrate(hash map[string]string, migrations map[int]Migration, target int) (migrated bool, err error) {
+	version := 0
+	if val, ok := hash[versionField]; ok {
+		version, err = strconv.Atoi(val)
+		if err != nil {
+			return false, &DecodeError{Field: versionField, Err: err}
+		}
+	}
+	for target == 0 || version < target {
+		migration, ok := migrations[version]
+		if !ok {
+			break
+		}
+		if err = migration(hash); err != nil {
+			return migrated, &DecodeError{Field: versionField, Err: err}
+		}
+		version++
+		migrated = true
+	}
+	if migrated {
+		hash[versionField] = strconv.Itoa(version)
+	}
+	return migrated, nil
+}
+
+// migrateHash migrates the hash read from key and writes it back if asked to.
+func (c *Client) migrateHash(ctx context.Context, key string, hash map[string]string, migrations map[int]Migration, target int, options Options) error {
+	original := make(map[string]string, len(hash))
+	for field, val := range hash {
+		original[field] = val
+	}
+	migrated, err := migrate(hash, migrations, target)
+	if err != nil {
+		return decodeError(err, key)
+	}
+	if !migrated || !options.WriteBackMigrations {
+		return nil
+	}
+	return c.writeBackHash(ctx, key, original, hash)
+}
+
+// writeBackScript sets or deletes hash fields when they all still hold the values read.
+// KEYS[1] is the hash, ARGV holds five values per field: the field, whether it was read,
+// the value read, whether it's set and the value to set. It returns whether it wrote.
+var writeBackScript = redis.NewScript(`
+local key = KEYS[1]
+for i = 1, #ARGV, 5 do
+	local current = redis.call('HGET', key, ARGV[i])
+	if (ARGV[i + 1] == '1' and current ~= ARGV[i + 2]) or (ARGV[i + 1] == '0' and current) then
+		return 0
+	end
+end
+for i = 1, #ARGV, 5 do
+	if ARGV[i + 3] == '1' then
+		redis.call('HSET', key, ARGV[i], ARGV[i + 4])
+	else
+		redis.call('HDEL', key, ARGV[i])
+	end
+end
+return 1
+`)
+
+// writeBackHash writes the fields changed or removed by a migration, the TTL is kept.
+// Nothing is written when any of these fields changed since it was read, the next
+// read migrates again.
+func (c *Client) writeBackHash(ctx context.Context, key string, original, hash map[string]string) error {
+	var args []interface{}
+	for field, old := range original {
+		if val, ok := hash[field]; !ok || val != old {
+			args = append(args, field, "1", old, flag(ok), val)
+		}
+	}
+	for field, val := range hash {
+		if _, ok := original[field]; !ok {
+			args = append(args, field, "0", "", "1", val)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return writeBackScript.Run(ctx, c.Cmdable, []string{key}, args...).Err()
+}
@@ -0,0 +1,231 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// versionedstruct is at version 2: v1 split "name" into "first" and "last",
+// v2 renamed "last" to "family".
+type versionedstruct struct {
+	First  string `json:"first"`
+	Family string `json:"family"`
+	Age    int    `json:"age"`
+}
+
+func (v *versionedstruct) SchemaVersion() int { return 2 }
+
+func newVersionedClient() *Client {
+	c := NewRedisClient(client.Cmdable)
+	c.RegisterMigration(versionedstruct{}, 0, func(hash map[string]string) error {
+		parts := strings.SplitN(hash["name"], " ", 2)
+		if len(parts) != 2 {
+			return errors.New("invalid name")
+		}
+		hash["first"], hash["last"] = parts[0], parts[1]
+		delete(hash, "name")
+		return nil
+	})
+	c.RegisterMigration(&versionedstruct{}, 1, func(hash map[string]string) error {
+		hash["family"] = hash["last"]
+		delete(hash, "last")
+		return nil
+	})
+	return c
+}
+
+func TestClient_Migrations(t *testing.T) {
+	ctx := context.Background()
+	c := newVersionedClient()
+	key := "kversionedstruct"
+	c.Del(ctx, key)
+	defer c.Del(ctx, key)
+
+	// a hash written before versioning
+	c.HSet(ctx, key, "name", "Ada Lovelace", "age", "36")
+	want := versionedstruct{First: "Ada", Family: "Lovelace", Age: 36}
+	var out versionedstruct
+	err := c.GetStructValue(ctx, key, &out, Strict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != want {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+	// the stored hash is left alone without write back
+	if n, _ := c.HExists(ctx, key, "name").Result(); !n {
+		t.Error("hash should not be written back")
+	}
+
+	out = versionedstruct{}
+	err = c.GetStructValue(ctx, key, &out, WriteBackMigrations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != want {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+	m, err := c.HGetAll(ctx, key).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHash := map[string]string{"first": "Ada", "family": "Lovelace", "age": "36", "_version": "2"}
+	if !reflect.DeepEqual(m, wantHash) {
+		t.Errorf("got %v, want %v", m, wantHash)
+	}
+
+	// new writes carry the version and aren't migrated again
+	err = c.SetStructValue(ctx, key, &versionedstruct{First: "Grace", Family: "Hopper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.HGet(ctx, key, "_version").Result(); v != "2" {
+		t.Errorf("_version = %q, want 2", v)
+	}
+	out = versionedstruct{}
+	err = c.GetStructValue(ctx, key, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Family != "Hopper" {
+		t.Errorf("got %+v", out)
+	}
+
+	// structs passed by value find the pointer method too
+	err = c.SetStructValue(ctx, key, versionedstruct{First: "Alan", Family: "Turing"}, UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.HGet(ctx, key, "_version").Result(); v != "2" {
+		t.Errorf("_version = %q, want 2", v)
+	}
+	out = versionedstruct{}
+	err = c.GetStructValue(ctx, key, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.First != "Alan" || out.Family != "Turing" {
+		t.Errorf("got %+v", out)
+	}
+}
+
+func TestClient_MigrationError(t *testing.T) {
+	ctx := context.Background()
+	c := newVersionedClient()
+	key := "kversionedstructerr"
+	c.HSet(ctx, key, "name", "Ada")
+	defer c.Del(ctx, key)
+
+	var out versionedstruct
+	err := c.GetStructValue(ctx, key, &out)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Key != key || decodeErr.Field != "_version" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestClient_UseSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	key := "kschemaversion"
+	defer client.Del(ctx, key)
+	err := client.SetStructValue(ctx, key, subkstruct{K: "v"}, UseSchemaVersion(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := client.HGet(ctx, key, "_version").Result(); v != "3" {
+		t.Errorf("_version = %q, want 3", v)
+	}
+	// the version is not an unknown field
+	var out subkstruct
+	if err = client.GetStructValue(ctx, key, &out, Strict()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	migrations := map[int]Migration{
+		0: func(hash map[string]string) error { hash["a"] = "1"; return nil },
+		1: func(hash map[string]string) error { hash["b"] = "2"; return nil },
+	}
+	// migrations stop at the target version
+	hash := map[string]string{}
+	migrated, err := migrate(hash, migrations, 1)
+	if err != nil || !migrated {
+		t.Fatal(migrated, err)
+	}
+	if !reflect.DeepEqual(hash, map[string]string{"a": "1", "_version": "1"}) {
+		t.Errorf("got %v", hash)
+	}
+	// and go as far as they can without target
+	migrated, err = migrate(hash, migrations, 0)
+	if err != nil || !migrated || hash["_version"] != "2" || hash["b"] != "2" {
+		t.Errorf("got %v, %v, %v", hash, migrated, err)
+	}
+	migrated, err = migrate(hash, migrations, 0)
+	if err != nil || migrated {
+		t.Errorf("got %v, %v", migrated, err)
+	}
+	if _, err = migrate(map[string]string{"_version": "x"}, migrations, 0); err == nil {
+		t.Error("invalid version should fail")
+	}
+}
+
+func TestMigrationRegistry_Concurrent(t *testing.T) {
+	c := NewRedisClient(client.Cmdable)
+	noop := func(hash map[string]string) error { return nil }
+	c.RegisterMigration(versionedstruct{}, 0, noop)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for from := 1; from < 100; from++ {
+			c.RegisterMigration(versionedstruct{}, from, noop)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		migrations := c.options.migrations.lookup(reflect.TypeOf(versionedstruct{}))
+		if _, err := migrate(map[string]string{}, migrations, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	if n := len(c.options.migrations.lookup(reflect.TypeOf(versionedstruct{}))); n != 100 {
+		t.Errorf("got %d migrations", n)
+	}
+}
+
+func TestClient_WriteBackHashConcurrent(t *testing.T) {
+	ctx := context.Background()
+	key := "kwritebackconcurrent"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	original := map[string]string{"name": "Ada Lovelace", "age": "36"}
+	migrated := map[string]string{"first": "Ada", "last": "Lovelace", "age": "36", "_version": "1"}
+
+	// a field the migration doesn't touch changed since the read, it's kept
+	client.HSet(ctx, key, "name", "Ada Lovelace", "age", "37")
+	if err := client.writeBackHash(ctx, key, original, migrated); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := client.HGetAll(ctx, key).Result()
+	want := map[string]string{"first": "Ada", "last": "Lovelace", "age": "37", "_version": "1"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+
+	// a field the migration touches changed since the read, nothing is written
+	client.Del(ctx, key)
+	client.HSet(ctx, key, "name", "Grace Hopper", "age", "36")
+	if err := client.writeBackHash(ctx, key, original, migrated); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = client.HGetAll(ctx, key).Result()
+	want = map[string]string{"name": "Grace Hopper", "age": "36"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+}
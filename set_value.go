@@ -1,13 +1,38 @@
 package redis
 
 import (
+	"encoding"
 	"encoding/json"
 	"reflect"
+	"sort"
 	"strconv"
 	"time"
 )
 
-func setValueByString(value reflect.Value, val string) error {
+func setValueByString(value reflect.Value, val string, options *Options) error {
+	if value.Kind() == reflect.Ptr {
+		// an empty value is read back as nil, as nil is written
+		if val == "" {
+			value.Set(reflect.Zero(value.Type()))
+			return nil
+		}
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setValueByString(value.Elem(), val, options)
+	}
+	if converter, ok := options.converters.lookup(value.Type()); ok && converter.Unmarshal != nil && value.CanAddr() {
+		return converter.Unmarshal([]byte(val), value.Addr().Interface())
+	}
+	switch value.Interface().(type) {
+	case time.Time:
+		return setTimeField(val, value, options.TimeFormat)
+	case time.Duration:
+		return setTimeDuration(val, value)
+	}
+	if ok, err := unmarshalValue(value, val); ok {
+		return err
+	}
 	switch value.Kind() {
 	case reflect.Int:
 		return setIntField(val, 0, value)
@@ -18,9 +43,6 @@ func setValueByString(value reflect.Value, val string) error {
 	case reflect.Int32:
 		return setIntField(val, 32, value)
 	case reflect.Int64:
-		if _, ok := value.Interface().(time.Duration); ok {
-			return setTimeDuration(val, value)
-		}
 		return setIntField(val, 64, value)
 	case reflect.Uint:
 		return setUintField(val, 0, value)
@@ -30,7 +52,7 @@ func setValueByString(value reflect.Value, val string) error {
 		return setUintField(val, 16, value)
 	case reflect.Uint32:
 		return setUintField(val, 32, value)
-	case reflect.Uint64:
+	case reflect.Uint64, reflect.Uintptr:
 		return setUintField(val, 64, value)
 	case reflect.Bool:
 		return setBoolField(val, value)
@@ -38,24 +60,49 @@ func setValueByString(value reflect.Value, val string) error {
 		return setFloatField(val, 32, value)
 	case reflect.Float64:
 		return setFloatField(val, 64, value)
+	case reflect.Complex64:
+		return setComplexField(val, 64, value)
+	case reflect.Complex128:
+		return setComplexField(val, 128, value)
 	case reflect.String:
 		value.SetString(val)
 	case reflect.Struct:
-		if _, ok := value.Interface().(time.Time); ok {
-			return setTimeField(val, value)
-		}
-		return json.Unmarshal(stringToBytes(val), value.Addr().Interface())
+		return options.Codec.Unmarshal(stringToBytes(val), value.Addr().Interface())
 	case reflect.Map, reflect.Array:
-		return json.Unmarshal(stringToBytes(val), value.Addr().Interface())
+		return options.Codec.Unmarshal(stringToBytes(val), value.Addr().Interface())
 	case reflect.Slice:
-		if _, ok := value.Interface().([]byte); ok {
+		if value.Type().Elem().Kind() == reflect.Uint8 {
 			return setByteSlice(val, value)
 		}
-		return json.Unmarshal(stringToBytes(val), value.Addr().Interface())
+		return options.Codec.Unmarshal(stringToBytes(val), value.Addr().Interface())
+	case reflect.Interface:
+		// empty interfaces hold the raw string
+		if value.NumMethod() == 0 {
+			value.Set(reflect.ValueOf(val))
+		}
 	}
 	return nil
 }
 
+// unmarshalValue decodes values implementing RedisUnmarshaler, encoding.BinaryUnmarshaler,
+// encoding.TextUnmarshaler or json.Unmarshaler, ok is false for the other values.
+func unmarshalValue(value reflect.Value, val string) (ok bool, err error) {
+	if !value.CanAddr() {
+		return false, nil
+	}
+	switch v := value.Addr().Interface().(type) {
+	case RedisUnmarshaler:
+		return true, v.UnmarshalRedis([]byte(val))
+	case encoding.BinaryUnmarshaler:
+		return true, v.UnmarshalBinary([]byte(val))
+	case encoding.TextUnmarshaler:
+		return true, v.UnmarshalText([]byte(val))
+	case json.Unmarshaler:
+		return true, v.UnmarshalJSON([]byte(val))
+	}
+	return false, nil
+}
+
 func setIntField(val string, bitSize int, field reflect.Value) error {
 	if val == "" {
 		val = "0"
@@ -100,8 +147,19 @@ func setFloatField(val string, bitSize int, field reflect.Value) error {
 	return err
 }
 
-func setTimeField(val string, value reflect.Value) error {
-	t, err := time.Parse(time.RFC3339Nano, val)
+func setComplexField(val string, bitSize int, field reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	complexVal, err := strconv.ParseComplex(val, bitSize)
+	if err == nil {
+		field.SetComplex(complexVal)
+	}
+	return err
+}
+
+func setTimeField(val string, value reflect.Value, format TimeFormat) error {
+	t, err := parseTime(val, format)
 	if err != nil {
 		return err
 	}
@@ -109,19 +167,19 @@ func setTimeField(val string, value reflect.Value) error {
 	return nil
 }
 
-func setArray(vals []string, value reflect.Value) error {
+func setArray(vals []string, value reflect.Value, options *Options) error {
 	for i, s := range vals {
-		err := setValueByString(value.Index(i), s)
+		err := setValueByString(value.Index(i), s, options)
 		if err != nil {
-			return err
+			return &DecodeError{Field: strconv.Itoa(i), Err: err}
 		}
 	}
 	return nil
 }
 
-func setSlice(vals []string, value reflect.Value) error {
+func setSlice(vals []string, value reflect.Value, options *Options) error {
 	slice := reflect.MakeSlice(value.Type(), len(vals), len(vals))
-	err := setArray(vals, slice)
+	err := setArray(vals, slice, options)
 	if err != nil {
 		return err
 	}
@@ -129,28 +187,47 @@ func setSlice(vals []string, value reflect.Value) error {
 	return nil
 }
 
-func setByteSlice(val string, value reflect.Value) error {
-	value.Set(reflect.ValueOf(stringToBytes(val)))
-	return nil
+// setMap decodes the keys and values of a hash into a map of any type,
+// allocating the map when it's nil. Entries that can't be decoded are left out
+// and reported in DecodeErrors, sorted by field.
+func setMap(vals map[string]string, value reflect.Value, options *Options) error {
+	if value.IsNil() {
+		value.Set(reflect.MakeMapWithSize(value.Type(), len(vals)))
+	}
+	if m, ok := value.Interface().(map[string]string); ok {
+		for k, v := range vals {
+			m[k] = v
+		}
+		return nil
+	}
+	keyType, elemType := value.Type().Key(), value.Type().Elem()
+	var errs DecodeErrors
+	for k, v := range vals {
+		mapKey := reflect.New(keyType).Elem()
+		err := setValueByString(mapKey, k, options)
+		if err == nil {
+			mapElem := reflect.New(elemType).Elem()
+			if err = setValueByString(mapElem, v, options); err == nil {
+				value.SetMapIndex(mapKey, mapElem)
+				continue
+			}
+		}
+		errs = append(errs, &DecodeError{Field: k, Err: err})
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs.err()
 }
 
-func setTimeDuration(val string, value reflect.Value) error {
-	d, err := time.ParseDuration(val)
-	if err != nil {
-		return err
-	}
-	value.Set(reflect.ValueOf(d))
+func setByteSlice(val string, value reflect.Value) error {
+	value.SetBytes([]byte(val))
 	return nil
 }
 
-func setTimeUnixNano(val string, value reflect.Value) error {
-	timeUnixNano, err := strconv.ParseInt(val, 10, 64)
+func setTimeDuration(val string, value reflect.Value) error {
+	d, err := parseDuration(val)
 	if err != nil {
 		return err
 	}
-	sec := timeUnixNano / 1000000000
-	nsec := timeUnixNano % 1000000000
-	d := time.Unix(sec, nsec)
-	value.Set(reflect.ValueOf(d))
+	value.SetInt(int64(d))
 	return nil
 }
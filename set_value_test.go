@@ -0,0 +1,285 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// kbinary marshals to 4 big endian bytes.
+type kbinary uint32
+
+func (k kbinary) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(k))
+	return b, nil
+}
+
+func (k *kbinary) UnmarshalBinary(b []byte) error {
+	if len(b) != 4 {
+		return errors.New("kbinary: invalid length")
+	}
+	*k = kbinary(binary.BigEndian.Uint32(b))
+	return nil
+}
+
+// kjson has pointer receivers only.
+type kjson struct {
+	Name string
+}
+
+func (k *kjson) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + k.Name + `"`), nil
+}
+
+func (k *kjson) UnmarshalJSON(b []byte) error {
+	k.Name = strings.Trim(string(b), `"`)
+	return nil
+}
+
+type marshalerstruct struct {
+	Kip     net.IP  `json:"kip"`
+	Kbinary kbinary `json:"kbinary"`
+	Kjson   kjson   `json:"kjson"`
+}
+
+func TestSetValueByString_Unmarshalers(t *testing.T) {
+	in := marshalerstruct{
+		Kip:     net.ParseIP("192.168.1.1"),
+		Kbinary: 0x01020304,
+		Kjson:   kjson{Name: "v"},
+	}
+	wants := []string{"192.168.1.1", "\x01\x02\x03\x04", `"v"`}
+	inValue := reflect.ValueOf(in)
+	var out marshalerstruct
+	outValue := reflect.ValueOf(&out).Elem()
+	for i, want := range wants {
+		bytes, err := toByte(inValue.Field(i), &client.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bytes) != want {
+			t.Errorf("field %d = %q, want %q", i, bytes, want)
+		}
+		err = setValueByString(outValue.Field(i), string(bytes), &client.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+}
+
+func TestClient_GetUnmarshalers(t *testing.T) {
+	in := marshalerstruct{
+		Kip:     net.ParseIP("::1"),
+		Kbinary: 42,
+		Kjson:   kjson{Name: "v"},
+	}
+	err := client.SetStructValue(context.Background(), "kmarshalerstruct", &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out marshalerstruct
+	err = client.GetStructValue(context.Background(), "kmarshalerstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	err = client.SetSingleValue(context.Background(), "kip", in.Kip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ip net.IP
+	err = client.GetSingleValue(context.Background(), "kip", &ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(in.Kip) {
+		t.Errorf("got %v, want %v", ip, in.Kip)
+	}
+}
+
+type pointerstruct struct {
+	Kint    *int         `json:"kint"`
+	Ktime   *time.Time   `json:"ktime"`
+	Kstring *string      `json:"kstring"`
+	Kstruct *subkstruct  `json:"kstruct"`
+	Kbinary *kbinary     `json:"kbinary"`
+	Kpsub   *flattenpsub `json:"kpsub,flatten"`
+	Kiface  interface{}  `json:"kiface"`
+	Kints   []*int       `json:"kints"`
+}
+
+func TestClient_SetStructValuePointers(t *testing.T) {
+	kint, kstring, kbinary := 1, "v", kbinary(2)
+	ktime := time.Now().UTC().Round(0)
+	in := pointerstruct{
+		Kint:    &kint,
+		Ktime:   &ktime,
+		Kstring: &kstring,
+		Kstruct: &subkstruct{K: "v", Ktime: ktime},
+		Kbinary: &kbinary,
+		Kpsub:   &flattenpsub{K: "p", Ktime: ktime},
+		Kiface:  "iface",
+		Kints:   []*int{&kint, &kint},
+	}
+	err := client.SetStructValue(context.Background(), "kpointerstruct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := client.HGetAll(context.Background(), "kpointerstruct").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["ktime"] != ktime.Format(time.RFC3339Nano) || m["kint"] != "1" || m["kints"] != "[1,1]" {
+		t.Errorf("pointers should be written as their values: %v", m)
+	}
+	var out pointerstruct
+	err = client.GetStructValue(context.Background(), "kpointerstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	// nil pointers are removed from the hash and read back as nil
+	err = client.SetStructValue(context.Background(), "kpointerstruct", pointerstruct{Kint: &kint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := client.HKeys(context.Background(), "kpointerstruct").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"kint", "kints"}) {
+		t.Errorf("got %v, want [kint kints]", keys)
+	}
+	out = pointerstruct{}
+	err = client.GetStructValue(context.Background(), "kpointerstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Kint == nil || *out.Kint != kint || out.Ktime != nil || out.Kstring != nil || out.Kstruct != nil || out.Kbinary != nil || out.Kpsub != nil {
+		t.Errorf("got %+v", out)
+	}
+}
+
+func TestClient_GetSliceValuePointers(t *testing.T) {
+	k1, k2 := 1, 2
+	err := client.SetSliceValue(context.Background(), "kpointer[]int", []*int{&k1, &k2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []*int
+	err = client.GetSliceValue(context.Background(), "kpointer[]int", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || *out[0] != k1 || *out[1] != k2 {
+		t.Errorf("got %v", out)
+	}
+
+	// nil elements are read back as nil
+	subs := []*subkstruct{nil, {K: "v"}}
+	if err = client.SetSliceValue(context.Background(), "kpointer[]struct", subs); err != nil {
+		t.Fatal(err)
+	}
+	var subsOut []*subkstruct
+	if err = client.GetSliceValue(context.Background(), "kpointer[]struct", &subsOut); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subsOut, subs) {
+		t.Errorf("got %v, want %v", subsOut, subs)
+	}
+	subMap := map[string]*subkstruct{"a": nil, "b": {K: "v"}}
+	if err = client.SetMapValue(context.Background(), "kpointermap", subMap); err != nil {
+		t.Fatal(err)
+	}
+	var subMapOut map[string]*subkstruct
+	if err = client.GetMapValue(context.Background(), "kpointermap", &subMapOut); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subMapOut, subMap) {
+		t.Errorf("got %v, want %v", subMapOut, subMap)
+	}
+}
+
+func TestClient_GetMapValueTypes(t *testing.T) {
+	in := map[int]subkstruct{
+		1: {K: "v1", K1: 1, Ktime: time.Now().UTC().Round(0)},
+		2: {K: "v2", K1: 2, Kduration: time.Second},
+	}
+	err := client.SetMapValue(context.Background(), "kmap[int]struct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[int]subkstruct
+	err = client.GetMapValue(context.Background(), "kmap[int]struct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	ipIn := map[kbinary]net.IP{1: net.ParseIP("10.0.0.1"), 2: net.ParseIP("::1")}
+	err = client.SetValue(context.Background(), "kmap[kbinary]ip", ipIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ipOut map[kbinary]net.IP
+	err = client.GetValue(context.Background(), "kmap[kbinary]ip", &ipOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ipIn, ipOut) {
+		t.Errorf("got %v, want %v", ipOut, ipIn)
+	}
+
+	boolIn := map[bool]*time.Duration{}
+	d := time.Minute
+	boolIn[true] = &d
+	err = client.SetMapValue(context.Background(), "kmap[bool]*duration", boolIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boolOut := map[bool]*time.Duration{}
+	err = client.GetMapValue(context.Background(), "kmap[bool]*duration", boolOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boolOut) != 1 || *boolOut[true] != d {
+		t.Errorf("got %v, want %v", boolOut, boolIn)
+	}
+
+	var nilMap map[string]int
+	err = client.GetMapValue(context.Background(), "kmap[bool]*duration", nilMap)
+	if err == nil {
+		t.Error("nil map that can't be allocated should fail")
+	}
+
+	// the entries that decode are kept, all the others are reported
+	client.HSet(context.Background(), "kmap[string]int", "a", "1", "b", "x", "c", "3", "d", "y")
+	var intOut map[string]int
+	err = client.GetMapValue(context.Background(), "kmap[string]int", &intOut)
+	var errs DecodeErrors
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Field != "b" || errs[1].Field != "d" || errs[0].Key != "kmap[string]int" {
+		t.Errorf("got %v", err)
+	}
+	if !reflect.DeepEqual(intOut, map[string]int{"a": 1, "c": 3}) {
+		t.Errorf("got %v", intOut)
+	}
+}
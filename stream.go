@@ -0,0 +1,172 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// AddStreamValue appends a struct or a map to a stream as an entry and returns its ID.
+// Struct fields are mapped as for SetStructValue, the ",streamid" string field is the ID of the
+// entry, generated when empty. The stream is trimmed with the StreamMaxLen and StreamMinID options.
+// Clients over a pipeline return an empty ID.
+func (c *Client) AddStreamValue(ctx context.Context, stream string, value interface{}, opts ...Option) (id string, err error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	valValue := reflect.ValueOf(value)
+	if valValue.Kind() == reflect.Ptr {
+		valValue = valValue.Elem()
+	}
+	args := &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: options.StreamMaxLen,
+		MinID:  options.StreamMinID,
+		Approx: options.StreamApprox,
+	}
+	switch valValue.Kind() {
+	case reflect.Struct:
+		plan := cachedStructPlan(valValue.Type(), &options)
+		m, _, err := plan.encode(valValue, &options)
+		if err != nil {
+			return "", err
+		}
+		if field, ok := plan.fieldBy(isStreamID); ok {
+			delete(m, field.key)
+			if idValue, ok := fieldByIndex(valValue, field.index); ok && reflect.Indirect(idValue).Kind() == reflect.String {
+				args.ID = reflect.Indirect(idValue).String()
+			}
+		}
+		args.Values = hashArgs(m)
+	case reflect.Map:
+		m := map[string]interface{}{}
+		iter := valValue.MapRange()
+		for iter.Next() {
+			k, err := toByte(iter.Key(), &options)
+			if err != nil {
+				return "", err
+			}
+			m[bytesToString(k)], err = toByte(iter.Value(), &options)
+			if err != nil {
+				return "", err
+			}
+		}
+		args.Values = hashArgs(m)
+	default:
+		return "", unsupportedTypeError(valValue, "struct or map")
+	}
+
+	var cmd *redis.StringCmd
+	err = c.txPipelined(ctx, func(pipe redis.Pipeliner) error {
+		cmd = pipe.XAdd(ctx, args)
+		if options.Expiration > 0 {
+			pipe.Expire(ctx, stream, options.Expiration)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return cmd.Val(), nil
+}
+
+// GetStreamValues reads the entries of a stream from start to end, "-" and "+" for all,
+// into a pointer to a slice of structs or maps. A count of 0 reads all the entries.
+// The entries that fail to decode are reported with fields prefixed by their ID, "id/field".
+// With the StreamReverse option entries are read from the end, start being the upper bound.
+func (c *Client) GetStreamValues(ctx context.Context, stream string, value interface{}, start, end string, count int64, opts ...Option) (err error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	valValue := reflect.ValueOf(value)
+	if valValue.Kind() != reflect.Ptr || valValue.IsNil() {
+		return ErrNotPointer
+	}
+	valValue = valValue.Elem()
+	if valValue.Kind() != reflect.Slice {
+		return unsupportedTypeError(valValue, "slice")
+	}
+	elemType := valValue.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct && structType.Kind() != reflect.Map {
+		return unsupportedTypeError(reflect.New(elemType).Elem(), "slice of structs or maps")
+	}
+
+	var msgs []redis.XMessage
+	switch {
+	case options.StreamReverse && count > 0:
+		msgs, err = c.XRevRangeN(ctx, stream, start, end, count).Result()
+	case options.StreamReverse:
+		msgs, err = c.XRevRange(ctx, stream, start, end).Result()
+	case count > 0:
+		msgs, err = c.XRangeN(ctx, stream, start, end, count).Result()
+	default:
+		msgs, err = c.XRange(ctx, stream, start, end).Result()
+	}
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		if err = c.exists(ctx, stream); err != nil {
+			return err
+		}
+	}
+
+	var errs DecodeErrors
+	slice := reflect.MakeSlice(valValue.Type(), len(msgs), len(msgs))
+	for i, msg := range msgs {
+		hash := make(map[string]string, len(msg.Values))
+		for k, v := range msg.Values {
+			hash[k], _ = v.(string)
+		}
+		elem := slice.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(structType))
+			elem = elem.Elem()
+		}
+		err = decodeStreamEntry(elem, msg.ID, hash, &options)
+		if err != nil {
+			errs = append(errs, entryErrors(err, msg.ID)...)
+		}
+	}
+	valValue.Set(slice)
+	return decodeError(errs.err(), stream)
+}
+
+// decodeStreamEntry decodes the values of an entry into a struct, setting its ",streamid" field, or a map.
+func decodeStreamEntry(elem reflect.Value, id string, hash map[string]string, options *Options) error {
+	if elem.Kind() == reflect.Map {
+		return setMap(hash, elem, options)
+	}
+	plan := cachedStructPlan(elem.Type(), options)
+	if field, ok := plan.fieldBy(isStreamID); ok {
+		hash[field.key] = id
+	}
+	err := plan.decodeHash(elem, hash, options)
+	applyDefaults(elem)
+	return err
+}
+
+// entryErrors prefixes the fields of the decode errors of a stream entry, or of an
+// element hash, with its ID.
+func entryErrors(err error, id string) DecodeErrors {
+	switch e := err.(type) {
+	case *DecodeError:
+		e.Field = id + "/" + e.Field
+		return DecodeErrors{e}
+	case DecodeErrors:
+		for _, fieldErr := range e {
+			fieldErr.Field = id + "/" + fieldErr.Field
+		}
+		return e
+	}
+	return DecodeErrors{{Field: id, Err: err}}
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type streamevent struct {
+	ID     string        `json:"id,streamid"`
+	Kind   string        `json:"kind"`
+	Count  int           `json:"count,omitempty"`
+	Took   time.Duration `json:"took"`
+	Source *string       `json:"source"`
+}
+
+func TestClient_StreamValues(t *testing.T) {
+	ctx := context.Background()
+	stream := "kstream"
+	client.Del(ctx, stream)
+	defer client.Del(ctx, stream)
+
+	source := "api"
+	in := []streamevent{
+		{ID: "1-1", Kind: "a", Count: 1, Took: time.Second, Source: &source},
+		{Kind: "b", Took: time.Millisecond},
+		{Kind: "c", Count: 3},
+	}
+	for i := range in {
+		id, err := client.AddStreamValue(ctx, stream, &in[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == "" || i == 0 && id != "1-1" {
+			t.Errorf("id = %q", id)
+		}
+		in[i].ID = id
+	}
+	msgs, err := client.XRange(ctx, stream, "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"kind": "a", "count": "1", "took": "1s", "source": "api"}
+	if !reflect.DeepEqual(msgs[0].Values, want) {
+		t.Errorf("got %v, want %v", msgs[0].Values, want)
+	}
+
+	var out []streamevent
+	err = client.GetStreamValues(ctx, stream, &out, "-", "+", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	var last []*streamevent
+	err = client.GetStreamValues(ctx, stream, &last, "+", "-", 2, StreamReverse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(last) != 2 || last[0].Kind != "c" || last[1].Kind != "b" || last[0].ID != in[2].ID {
+		t.Errorf("got %+v", last)
+	}
+
+	var maps []map[string]string
+	err = client.GetStreamValues(ctx, stream, &maps, "-", "+", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 1 || maps[0]["kind"] != "a" {
+		t.Errorf("got %v", maps)
+	}
+
+	_, err = client.AddStreamValue(ctx, stream, map[string]int{"count": 4}, StreamMaxLen(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.XLen(ctx, stream).Result(); n != 2 {
+		t.Errorf("len = %d, want 2", n)
+	}
+}
+
+func TestClient_StreamValuesErrors(t *testing.T) {
+	ctx := context.Background()
+	stream := "kstreamerrors"
+	client.Del(ctx, stream)
+	defer client.Del(ctx, stream)
+
+	var out []streamevent
+	if err := client.GetStreamValues(ctx, stream, &out, "-", "+", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if err := client.GetStreamValues(ctx, stream, out, "-", "+", 0); !errors.Is(err, ErrNotPointer) {
+		t.Errorf("got %v, want ErrNotPointer", err)
+	}
+	var ints []int
+	if err := client.GetStreamValues(ctx, stream, &ints, "-", "+", 0); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+
+	client.XAdd(ctx, &redis.XAddArgs{Stream: stream, ID: "1-0", Values: []interface{}{"kind", "a", "count", "x"}})
+	client.XAdd(ctx, &redis.XAddArgs{Stream: stream, ID: "2-0", Values: []interface{}{"kind", "b"}})
+	err := client.GetStreamValues(ctx, stream, &out, "-", "+", 0)
+	var errs DecodeErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Key != stream || errs[0].Field != "1-0/count" {
+		t.Errorf("got %v", err)
+	}
+	// the good entries are still decoded
+	if len(out) != 2 || out[0].Kind != "a" || out[1].ID != "2-0" {
+		t.Errorf("got %+v", out)
+	}
+}
@@ -0,0 +1,296 @@
+package redis
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// structField is a hash field mapped to a field of a struct,
+// index is the path to the field through flattened and inlined structs.
+type structField struct {
+	key   string
+	index []int
+	// name is the Go name of the field, "Parent.Child" for flattened fields.
+	name string
+	// omitEmpty skips the field on write when it holds the zero value.
+	omitEmpty bool
+	// quoted stores scalars as quoted strings.
+	quoted bool
+	// required fields must be present in strict mode.
+	required bool
+	// score is the sorted set score of the struct.
+	score bool
+	// streamID is the ID of the stream entry of the struct.
+	streamID bool
+	// pk is the primary key of the struct in the key of its element hash.
+	pk bool
+	// companion is the kind of the companion key holding the field, empty for hash fields.
+	companion string
+	// defaultValue is decoded when the field is missing from the hash and hasDefault is set.
+	defaultValue string
+	hasDefault   bool
+	// tagged is set when the key comes from the tag, it wins name conflicts.
+	tagged  bool
+	encoder encoderFunc
+	decoder decoderFunc
+}
+
+// matches reports whether name is the hash field or the Go name of the field.
+func (f *structField) matches(name string) bool {
+	return name == f.key || name == f.name
+}
+
+// selected reports whether the field is kept by the Fields and Omit options.
+func (f *structField) selected(options *Options) bool {
+	if len(options.Fields) > 0 && !f.matchesAny(options.Fields) {
+		return false
+	}
+	return !f.matchesAny(options.Omit)
+}
+
+func (f *structField) matchesAny(names []string) bool {
+	for _, name := range names {
+		if f.matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// encode converts the value of the field to its hash field value.
+func (f *structField) encode(value reflect.Value, options *Options) ([]byte, error) {
+	bytes, err := f.encoder(value, options)
+	if err != nil || !f.quoted {
+		return bytes, err
+	}
+	return strconv.AppendQuote(nil, bytesToString(bytes)), nil
+}
+
+// decode sets the value of the field from its hash field value,
+// quoted fields also accept unquoted values.
+func (f *structField) decode(value reflect.Value, val string, options *Options) error {
+	if f.quoted {
+		if s, err := strconv.Unquote(val); err == nil {
+			val = s
+		}
+	}
+	return f.decoder(value, val, options)
+}
+
+// getStructFields lists the hash fields of a struct type.
+// Fields of embedded structs are promoted following the rules of encoding/json.
+func getStructFields(valType reflect.Type, options *Options) []structField {
+	visited := map[reflect.Type]bool{valType: true}
+	fields := appendStructFields(nil, valType, "", "", nil, options, visited)
+	return dominantFields(fields)
+}
+
+func appendStructFields(fields []structField, valType reflect.Type, prefix, namePrefix string, index []int, options *Options, visited map[reflect.Type]bool) []structField {
+	numField := valType.NumField()
+	for i := 0; i < numField; i++ {
+		field := valType.Field(i)
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				if field.PkgPath != "" {
+					// embedded pointers to unexported types can't be allocated
+					continue
+				}
+				fieldType = fieldType.Elem()
+			}
+			if field.PkgPath != "" && fieldType.Kind() != reflect.Struct {
+				continue
+			}
+		} else if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		key, opts, tagged := getStructKey(field, options.Tag)
+		if key == "" {
+			continue
+		}
+		fieldIndex := append(index[:len(index):len(index)], i)
+		if opts.Contains("inline") || field.Anonymous && !tagged {
+			// inlined and embedded struct fields are promoted without prefix.
+			if structType, ok := flattenType(field.Type, options); ok && !visited[structType] {
+				visited[structType] = true
+				fields = appendStructFields(fields, structType, prefix, namePrefix, fieldIndex, options, visited)
+				delete(visited, structType)
+				continue
+			}
+		}
+		if options.Flatten || opts.Contains("flatten") {
+			// nested structs become "parent.child" fields, recursive types are encoded as a whole.
+			if structType, ok := flattenType(field.Type, options); ok && !visited[structType] {
+				visited[structType] = true
+				fields = appendStructFields(fields, structType, prefix+key+".", namePrefix+field.Name+".", fieldIndex, options, visited)
+				delete(visited, structType)
+				continue
+			}
+		}
+		encoder, decoder, ok := formatEncoder(field.Type, opts)
+		if !ok {
+			encoder, decoder = typeEncoder(field.Type), typeDecoder(field.Type)
+		}
+		defaultValue, hasDefault := field.Tag.Lookup(defaultTag)
+		fields = append(fields, structField{
+			key:          prefix + key,
+			name:         namePrefix + field.Name,
+			index:        fieldIndex,
+			omitEmpty:    opts.Contains("omitempty"),
+			quoted:       opts.Contains("string") && isQuotableKind(field.Type.Kind()),
+			required:     opts.Contains("required"),
+			score:        opts.Contains("score"),
+			streamID:     opts.Contains("streamid"),
+			pk:           opts.Contains("pk"),
+			companion:    companionKind(opts),
+			defaultValue: defaultValue,
+			hasDefault:   hasDefault,
+			tagged:       tagged,
+			encoder:      encoder,
+			decoder:      decoder,
+		})
+	}
+	return fields
+}
+
+// companionKind returns the kind of the companion key of the ",list", ",set", ",hash" or ",zset" tag options.
+func companionKind(opts tagOptions) string {
+	for _, kind := range []string{kindList, kindSet, kindHash, kindZSet} {
+		if opts.Contains(kind) {
+			return kind
+		}
+	}
+	return ""
+}
+
+// dominantFields resolves fields sharing a key: the shallowest field wins,
+// then the tagged one, otherwise none of them is mapped.
+func dominantFields(fields []structField) []structField {
+	byKey := make(map[string][]int, len(fields))
+	for i, field := range fields {
+		byKey[field.key] = append(byKey[field.key], i)
+	}
+	if len(byKey) == len(fields) {
+		return fields
+	}
+	keep := make([]bool, len(fields))
+	for _, idxs := range byKey {
+		if len(idxs) == 1 {
+			keep[idxs[0]] = true
+			continue
+		}
+		depth := len(fields[idxs[0]].index)
+		for _, i := range idxs[1:] {
+			if len(fields[i].index) < depth {
+				depth = len(fields[i].index)
+			}
+		}
+		dominant, count, tagged := -1, 0, 0
+		for _, i := range idxs {
+			if len(fields[i].index) != depth {
+				continue
+			}
+			count++
+			if fields[i].tagged {
+				dominant = i
+				tagged++
+			} else if dominant == -1 {
+				dominant = i
+			}
+		}
+		if count == 1 || tagged == 1 {
+			keep[dominant] = true
+		}
+	}
+	dominants := make([]structField, 0, len(byKey))
+	for i, field := range fields {
+		if keep[i] {
+			dominants = append(dominants, field)
+		}
+	}
+	return dominants
+}
+
+// flattenType returns the struct type of a struct or pointer to struct field
+// that can be flattened, types with their own wire format can't.
+func flattenType(fieldType reflect.Type, options *Options) (reflect.Type, bool) {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() != reflect.Struct || fieldType == timeType || isRedisScalar(fieldType, options) {
+		return nil, false
+	}
+	if implementsMarshaler(fieldType) || implementsMarshaler(reflect.PtrTo(fieldType)) {
+		return nil, false
+	}
+	return fieldType, true
+}
+
+// fieldByIndex returns the nested field of value,
+// ok is false when a nil pointer to a flattened struct is on the way.
+func fieldByIndex(value reflect.Value, index []int) (field reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
+
+// fieldByIndexAlloc returns the nested field of value,
+// allocating nil pointers to flattened structs on the way.
+func fieldByIndexAlloc(value reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value
+}
+
+// isQuotableKind reports whether the ",string" tag option applies to a kind.
+func isQuotableKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return false
+}
+
+func isNilValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
@@ -0,0 +1,406 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type flattenstruct struct {
+	K        string       `json:"k"`
+	Kstruct  subkstruct   `json:"kstruct,flatten"`
+	Kpstruct *flattenpsub `json:"kpstruct,flatten"`
+	Kblob    subkstruct   `json:"kblob"`
+}
+
+type flattenpsub struct {
+	K     string    `json:"k"`
+	Ktime time.Time `json:"ktime"`
+}
+
+type flattennode struct {
+	K     string       `json:"k"`
+	Knext *flattennode `json:"knext,flatten"`
+}
+
+func TestGetStructFields(t *testing.T) {
+	var keys []string
+	for _, field := range getStructFields(reflect.TypeOf(flattenstruct{}), &client.options) {
+		keys = append(keys, field.key)
+	}
+	want := []string{
+		"k",
+		"kstruct.k", "kstruct.k_1", "kstruct.k_1_1", "kstruct.ktrue", "kstruct.ktime", "kstruct.kduration",
+		"kpstruct.k", "kpstruct.ktime",
+		"kblob",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+
+	// recursive types are encoded as a whole
+	keys = keys[:0]
+	for _, field := range getStructFields(reflect.TypeOf(flattennode{}), &client.options) {
+		keys = append(keys, field.key)
+	}
+	want = []string{"k", "knext"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+}
+
+func TestClient_SetStructValueFlatten(t *testing.T) {
+	in := flattenstruct{
+		K: "v",
+		Kstruct: subkstruct{
+			K:         "v",
+			K1:        1,
+			K1_1:      1.1,
+			Ktrue:     true,
+			Ktime:     time.Now().UTC().Round(0),
+			Kduration: time.Second,
+		},
+		Kpstruct: &flattenpsub{K: "p", Ktime: time.Now().UTC().Round(0)},
+	}
+	err := client.SetStructValue(context.Background(), "kflattenstruct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1, err := client.HGet(context.Background(), "kflattenstruct", "kstruct.k_1").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k1 != "1" {
+		t.Errorf("kstruct.k_1 = %q, want %q", k1, "1")
+	}
+	err = client.HIncrBy(context.Background(), "kflattenstruct", "kstruct.k_1", 1).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out flattenstruct
+	err = client.GetStructValue(context.Background(), "kflattenstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Kstruct.K1++
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	in.Kpstruct = nil
+	err = client.SetStructValue(context.Background(), "kflattenstructnil", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = flattenstruct{}
+	err = client.GetStructValue(context.Background(), "kflattenstructnil", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Kpstruct != nil {
+		t.Errorf("kpstruct = %+v, want nil", out.Kpstruct)
+	}
+}
+
+func TestClient_SetStructValueFlattenOption(t *testing.T) {
+	in := kstruct{
+		K: "v",
+		Kstruct: subkstruct{
+			K:         "v",
+			K1:        1,
+			Kduration: time.Second,
+		},
+	}
+	err := client.SetStructValue(context.Background(), "kflattenoption", in, Flatten())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := client.HGet(context.Background(), "kflattenoption", "kstruct.kduration").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != "1s" {
+		t.Errorf("kstruct.kduration = %q, want %q", k, "1s")
+	}
+	var out kstruct
+	err = client.GetStructValue(context.Background(), "kflattenoption", &out, Flatten())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+}
+
+type tagstruct struct {
+	K           string    `json:"k,omitempty"`
+	K1          int       `json:"k_1,string"`
+	K1_1        float64   `json:"k_1_1" redis:"kfloat"`
+	Kskip       string    `json:"kskip" redis:"-"`
+	Kname       bool      `json:",omitempty"`
+	Kinline     inlinesub `json:",inline"`
+	kunexported string
+}
+
+type inlinesub struct {
+	Kin   string    `json:"kin"`
+	Ktime time.Time `json:"ktime"`
+}
+
+func TestGetStructKey(t *testing.T) {
+	valType := reflect.TypeOf(tagstruct{})
+	tests := []struct {
+		field     string
+		tag       string
+		key       string
+		omitEmpty bool
+	}{
+		{"K", "json", "k", true},
+		{"K1", "json", "k_1", false},
+		{"K1_1", "json", "kfloat", false},
+		{"K1_1", "", "kfloat", false},
+		{"Kskip", "json", "", false},
+		{"Kname", "json", "Kname", true},
+		{"K", "", "K", false},
+	}
+	for _, tt := range tests {
+		field, _ := valType.FieldByName(tt.field)
+		key, opts, _ := getStructKey(field, tt.tag)
+		if key != tt.key || opts.Contains("omitempty") != tt.omitEmpty {
+			t.Errorf("getStructKey(%s, %q) = %q, %q", tt.field, tt.tag, key, opts)
+		}
+	}
+}
+
+func TestClient_SetStructValueTagOptions(t *testing.T) {
+	in := tagstruct{
+		K:           "v",
+		K1:          1,
+		K1_1:        1.1,
+		Kskip:       "skip",
+		Kname:       true,
+		Kinline:     inlinesub{Kin: "inline", Ktime: time.Now().UTC().Round(0)},
+		kunexported: "unexported",
+	}
+	err := client.SetStructValue(context.Background(), "ktagstruct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := client.HGetAll(context.Background(), "ktagstruct").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"k":      "v",
+		"k_1":    `"1"`,
+		"kfloat": "1.1",
+		"Kname":  "true",
+		"kin":    "inline",
+		"ktime":  in.Kinline.Ktime.Format(time.RFC3339Nano),
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+
+	// empty omitempty fields are removed from the hash
+	in.K = ""
+	in.Kname = false
+	err = client.SetStructValue(context.Background(), "ktagstruct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out tagstruct
+	err = client.GetStructValue(context.Background(), "ktagstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Kskip = ""
+	in.kunexported = ""
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	// ",string" fields also read unquoted values
+	err = client.HSet(context.Background(), "ktagstruct", "k_1", "2").Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.GetStructValue(context.Background(), "ktagstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.K1 != 2 {
+		t.Errorf("k_1 = %d, want 2", out.K1)
+	}
+}
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"`
+}
+
+type Owner struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+type embeddedstruct struct {
+	Audit
+	*Owner
+	K       string `json:"k"`
+	Version string `json:"version"`
+	Kdup
+	Kdup2
+}
+
+type Kdup struct {
+	Dup  string `json:"dup"`
+	Kdup string `json:"kdup"`
+}
+
+type Kdup2 struct {
+	Dup string `redis:"dup"`
+}
+
+func TestGetStructFieldsEmbedded(t *testing.T) {
+	var keys []string
+	for _, field := range getStructFields(reflect.TypeOf(embeddedstruct{}), &client.options) {
+		keys = append(keys, field.key)
+	}
+	// "version" is taken by the shallowest field, "dup" is ambiguous
+	want := []string{"created_at", "updated_at", "name", "k", "version", "kdup"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+}
+
+func TestClient_SetStructValueEmbedded(t *testing.T) {
+	in := embeddedstruct{
+		Audit: Audit{
+			CreatedAt: time.Now().UTC().Round(0),
+			UpdatedAt: time.Now().UTC().Round(0),
+			Version:   1,
+		},
+		Owner:   &Owner{Name: "owner", Version: 2},
+		K:       "v",
+		Version: "v3",
+		Kdup:    Kdup{Dup: "dup", Kdup: "kdup"},
+	}
+	err := client.SetStructValue(context.Background(), "kembeddedstruct", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt, err := client.HGet(context.Background(), "kembeddedstruct", "created_at").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if createdAt != in.CreatedAt.Format(time.RFC3339Nano) {
+		t.Errorf("created_at = %q", createdAt)
+	}
+
+	var out embeddedstruct
+	err = client.GetStructValue(context.Background(), "kembeddedstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// shadowed and ambiguous fields are not mapped
+	in.Audit.Version, in.Owner.Version, in.Kdup.Dup = 0, 0, ""
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+	if out.Owner == nil || out.Owner.Name != "owner" {
+		t.Errorf("embedded pointer should be allocated: %+v", out.Owner)
+	}
+}
+
+type defaultstruct struct {
+	K        string        `json:"k"`
+	Kcount   int           `json:"kcount" default:"10"`
+	Kratio   float64       `json:"kratio,string" default:"0.5"`
+	Kenabled *bool         `json:"kenabled" default:"true"`
+	Ktimeout time.Duration `json:"ktimeout" default:"1m30s"`
+	Ktags    []string      `json:"ktags" default:"[\"a\",\"b\"]"`
+	Kname    string        `json:"kname" default:"none"`
+	Kapplied bool          `json:"-"`
+	Kderived string        `json:"kderived"`
+}
+
+func (d *defaultstruct) ApplyDefaults() {
+	d.Kapplied = true
+	if d.Kderived == "" {
+		d.Kderived = d.K + "-derived"
+	}
+}
+
+func TestClient_GetStructValueDefaults(t *testing.T) {
+	ctx := context.Background()
+	key := "kdefaultstruct"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	// an old hash written before the fields with defaults existed
+	client.HSet(ctx, key, "k", "v", "kname", "")
+
+	var out defaultstruct
+	err := client.GetStructValue(ctx, key, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := true
+	want := defaultstruct{
+		K:        "v",
+		Kcount:   10,
+		Kratio:   0.5,
+		Kenabled: &enabled,
+		Ktimeout: 90 * time.Second,
+		Ktags:    []string{"a", "b"},
+		Kname:    "", // present, so not defaulted
+		Kapplied: true,
+		Kderived: "v-derived",
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+
+	client.HSet(ctx, key, "kcount", "3")
+	out = defaultstruct{}
+	err = client.GetStructValue(ctx, key, &out, Strict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Kcount != 3 || out.Kratio != 0.5 || !out.Kapplied {
+		t.Errorf("got %+v", out)
+	}
+
+	// the hook isn't called for missing keys
+	client.Del(ctx, key)
+	out = defaultstruct{}
+	if err = client.GetStructValue(ctx, key, &out); !errors.Is(err, ErrNotFound) || out.Kapplied {
+		t.Errorf("got %v, %+v", err, out)
+	}
+}
+
+type baddefaultstruct struct {
+	K      string `json:"k"`
+	Kcount int    `json:"kcount" default:"ten"`
+}
+
+func TestClient_GetStructValueBadDefault(t *testing.T) {
+	ctx := context.Background()
+	key := "kbaddefaultstruct"
+	client.HSet(ctx, key, "k", "v")
+	defer client.Del(ctx, key)
+
+	var out baddefaultstruct
+	err := client.GetStructValue(ctx, key, &out)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Field != "kcount" {
+		t.Errorf("got %v", err)
+	}
+	if out.K != "v" {
+		t.Errorf("got %+v", out)
+	}
+}
@@ -0,0 +1,297 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// structPlan is the mapping of a struct type to hash fields,
+// it's built once per type and options and shared by reads and writes.
+type structPlan struct {
+	fields []structField
+	// keys are the hash field names in the order of fields.
+	keys []string
+	// byKey maps the hash field names to their index in fields,
+	// projections share the one of their full plan.
+	byKey map[string]int
+	// companions are the fields stored in companion keys rather than in the hash.
+	companions []structField
+}
+
+type structPlanKey struct {
+	valType reflect.Type
+	tag     string
+	flatten bool
+}
+
+// structPlanCache holds the plans built without converters. Converters change plans,
+// so the plans of a client live in its converter registry and go away with it.
+var structPlanCache sync.Map // map[structPlanKey]*structPlan
+
+// cachedStructPlan returns the plan of a struct type, building it on first use.
+func cachedStructPlan(valType reflect.Type, options *Options) *structPlan {
+	cache := &structPlanCache
+	if options.converters != nil {
+		cache = &options.converters.plans
+	}
+	key := structPlanKey{valType: valType, tag: options.Tag, flatten: options.Flatten}
+	if plan, ok := cache.Load(key); ok {
+		return plan.(*structPlan)
+	}
+	plan, _ := cache.LoadOrStore(key, newStructPlan(valType, options))
+	return plan.(*structPlan)
+}
+
+func newStructPlan(valType reflect.Type, options *Options) *structPlan {
+	plan := &structPlan{byKey: map[string]int{}}
+	for _, field := range getStructFields(valType, options) {
+		if field.companion != "" {
+			plan.companions = append(plan.companions, field)
+			continue
+		}
+		plan.byKey[field.key] = len(plan.fields)
+		plan.fields = append(plan.fields, field)
+		plan.keys = append(plan.keys, field.key)
+	}
+	return plan
+}
+
+// project returns the plan of the fields selected by the Fields and Omit options,
+// p itself without them. Names matching no field are reported as ErrUnknownField.
+func (p *structPlan) project(options *Options) (*structPlan, error) {
+	if !options.partial() {
+		return p, nil
+	}
+	all := append(p.fields[:len(p.fields):len(p.fields)], p.companions...)
+	for _, names := range [][]string{options.Fields, options.Omit} {
+	next:
+		for _, name := range names {
+			for i := range all {
+				if all[i].matches(name) {
+					continue next
+				}
+			}
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+	}
+	projection := &structPlan{byKey: p.byKey}
+	for _, field := range p.fields {
+		if field.selected(options) {
+			projection.fields = append(projection.fields, field)
+			projection.keys = append(projection.keys, field.key)
+		}
+	}
+	for _, field := range p.companions {
+		if field.selected(options) {
+			projection.companions = append(projection.companions, field)
+		}
+	}
+	return projection, nil
+}
+
+// fieldBy returns the first field matching.
+func (p *structPlan) fieldBy(match func(field *structField) bool) (field *structField, ok bool) {
+	for i := range p.fields {
+		if match(&p.fields[i]) {
+			return &p.fields[i], true
+		}
+	}
+	return nil, false
+}
+
+func isScore(field *structField) bool { return field.score }
+
+func isStreamID(field *structField) bool { return field.streamID }
+
+func isPK(field *structField) bool { return field.pk }
+
+// encode converts a struct to hash field values, omitted lists the absent
+// fields: nil pointers and empty omitempty fields.
+func (p *structPlan) encode(valValue reflect.Value, options *Options) (m map[string]interface{}, omitted []string, err error) {
+	m = make(map[string]interface{}, len(p.fields))
+	for i := range p.fields {
+		field := &p.fields[i]
+		fieldValue, ok := fieldByIndex(valValue, field.index)
+		if !ok || isNilValue(fieldValue) || field.omitEmpty && isEmptyValue(fieldValue) {
+			omitted = append(omitted, field.key)
+			continue
+		}
+		m[field.key], err = field.encode(fieldValue, options)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return m, omitted, nil
+}
+
+// decode sets the struct fields from the values of p.keys as returned by HMGET.
+// It goes on after a failing field, the failures are returned as DecodeErrors.
+func (p *structPlan) decode(valValue reflect.Value, vals []interface{}, options *Options) error {
+	if len(p.fields) != len(vals) {
+		return errors.New("HMGet should have the same number of keys and vals")
+	}
+	return p.decodeFields(valValue, vals, options).err()
+}
+
+// decodeHash sets the struct fields from the values returned by HGETALL,
+// in strict mode the hash fields without struct field are reported.
+func (p *structPlan) decodeHash(valValue reflect.Value, hash map[string]string, options *Options) error {
+	vals := make([]interface{}, len(p.keys))
+	for i, key := range p.keys {
+		if val, ok := hash[key]; ok {
+			vals[i] = val
+		}
+	}
+	errs := p.decodeFields(valValue, vals, options)
+	if options.Strict {
+		var unknown []string
+		for key := range hash {
+			if _, ok := p.byKey[key]; !ok && key != versionField {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			errs = append(errs, &DecodeError{Field: key, Err: ErrUnknownField})
+		}
+	}
+	return errs.err()
+}
+
+func (p *structPlan) decodeFields(valValue reflect.Value, vals []interface{}, options *Options) (errs DecodeErrors) {
+	for i := range vals {
+		field := &p.fields[i]
+		valStr, ok := vals[i].(string)
+		if !ok {
+			if options.Strict && field.required {
+				errs = append(errs, &DecodeError{Field: field.key, Err: ErrMissingField})
+			}
+			if field.hasDefault {
+				// defaults are plain values, never quoted
+				err := field.decoder(fieldByIndexAlloc(valValue, field.index), field.defaultValue, options)
+				if err != nil {
+					errs = append(errs, &DecodeError{Field: field.key, Err: err})
+				}
+			}
+			continue
+		}
+		err := field.decode(fieldByIndexAlloc(valValue, field.index), valStr, options)
+		if err != nil {
+			errs = append(errs, &DecodeError{Field: field.key, Err: err})
+		}
+	}
+	return errs
+}
+
+// DefaultsApplier is implemented by structs that set their own defaults,
+// ApplyDefaults is called once the fields are read from the hash, after the default tags.
+type DefaultsApplier interface {
+	ApplyDefaults()
+}
+
+// applyDefaults calls ApplyDefaults on the struct or its address.
+func applyDefaults(valValue reflect.Value) {
+	if valValue.CanAddr() {
+		valValue = valValue.Addr()
+	}
+	if applier, ok := valValue.Interface().(DefaultsApplier); ok {
+		applier.ApplyDefaults()
+	}
+}
+
+type encoderFunc func(value reflect.Value, options *Options) ([]byte, error)
+
+type decoderFunc func(value reflect.Value, val string, options *Options) error
+
+// typeEncoder returns a fast path for builtin scalar types, the others go through toByte.
+func typeEncoder(valType reflect.Type) encoderFunc {
+	if !isBuiltinType(valType) {
+		return toByte
+	}
+	switch valType.Kind() {
+	case reflect.String:
+		return encodeString
+	case reflect.Bool:
+		return encodeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encodeInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return encodeUint
+	case reflect.Float32, reflect.Float64:
+		return encodeFloat
+	}
+	return toByte
+}
+
+// typeDecoder returns a fast path for builtin scalar types, the others go through setValueByString.
+func typeDecoder(valType reflect.Type) decoderFunc {
+	if !isBuiltinType(valType) {
+		return setValueByString
+	}
+	bitSize := 0
+	switch valType.Kind() {
+	case reflect.String:
+		return decodeString
+	case reflect.Bool:
+		return decodeBool
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bitSize = valType.Bits()
+		fallthrough
+	case reflect.Int:
+		return func(value reflect.Value, val string, _ *Options) error {
+			return setIntField(val, bitSize, value)
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bitSize = valType.Bits()
+		fallthrough
+	case reflect.Uint:
+		return func(value reflect.Value, val string, _ *Options) error {
+			return setUintField(val, bitSize, value)
+		}
+	case reflect.Float32, reflect.Float64:
+		bitSize = valType.Bits()
+		return func(value reflect.Value, val string, _ *Options) error {
+			return setFloatField(val, bitSize, value)
+		}
+	}
+	return setValueByString
+}
+
+// isBuiltinType reports whether t is a predeclared type like int or string,
+// named types may carry marshaling methods and take the generic path.
+func isBuiltinType(t reflect.Type) bool {
+	return t.PkgPath() == "" && t.Name() != ""
+}
+
+func encodeString(value reflect.Value, _ *Options) ([]byte, error) {
+	return stringToBytes(value.String()), nil
+}
+
+func encodeBool(value reflect.Value, _ *Options) ([]byte, error) {
+	return strconv.AppendBool(nil, value.Bool()), nil
+}
+
+func encodeInt(value reflect.Value, _ *Options) ([]byte, error) {
+	return strconv.AppendInt(nil, value.Int(), 10), nil
+}
+
+func encodeUint(value reflect.Value, _ *Options) ([]byte, error) {
+	return strconv.AppendUint(nil, value.Uint(), 10), nil
+}
+
+func encodeFloat(value reflect.Value, _ *Options) ([]byte, error) {
+	return strconv.AppendFloat(nil, value.Float(), 'f', -1, 64), nil
+}
+
+func decodeString(value reflect.Value, val string, _ *Options) error {
+	value.SetString(val)
+	return nil
+}
+
+func decodeBool(value reflect.Value, val string, _ *Options) error {
+	return setBoolField(val, value)
+}
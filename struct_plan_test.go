@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type planstruct struct {
+	K     string  `json:"k"`
+	K1    int     `json:"k_1"`
+	K8    int8    `json:"k_8"`
+	Ku    uint16  `json:"k_u"`
+	K1_1  float32 `json:"k_1_1"`
+	Ktrue bool    `json:"ktrue"`
+	Audit
+}
+
+var testPlanStruct = planstruct{
+	K:     "v",
+	K1:    -1,
+	K8:    8,
+	Ku:    16,
+	K1_1:  1.1,
+	Ktrue: true,
+	Audit: Audit{CreatedAt: time.Now().UTC().Round(0), Version: 1},
+}
+
+func TestCachedStructPlan(t *testing.T) {
+	valType := reflect.TypeOf(planstruct{})
+	options := client.options
+	plan := cachedStructPlan(valType, &options)
+	if cachedStructPlan(valType, &options) != plan {
+		t.Error("plan should be cached")
+	}
+	options.Tag = "redis"
+	if cachedStructPlan(valType, &options) == plan {
+		t.Error("plan should be cached per tag")
+	}
+
+	var wg sync.WaitGroup
+	plans := make([]*structPlan, 8)
+	for i := range plans {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			plans[i] = cachedStructPlan(reflect.TypeOf(embeddedstruct{}), &client.options)
+		}(i)
+	}
+	wg.Wait()
+	for _, p := range plans[1:] {
+		if p != plans[0] {
+			t.Error("concurrent callers should share the plan")
+		}
+	}
+}
+
+func TestCachedStructPlan_PerClient(t *testing.T) {
+	ctx := context.Background()
+	key := "kplanperclient"
+	defer client.Del(ctx, key)
+
+	type perclientstruct struct {
+		K string `json:"k"`
+	}
+	valType := reflect.TypeOf(perclientstruct{})
+	for i := 0; i < 3; i++ {
+		pipe := client.Pipeline()
+		c := NewRedisClient(pipe)
+		if err := c.SetStructValue(ctx, key, perclientstruct{K: "v"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := c.options.converters.plans.Load(structPlanKey{valType: valType, tag: "json"}); !ok {
+			t.Error("plan should be cached by the client")
+		}
+	}
+	structPlanCache.Range(func(key, _ interface{}) bool {
+		if key.(structPlanKey).valType == valType {
+			t.Error("plans of clients should not be cached globally")
+		}
+		return true
+	})
+}
+
+func TestStructPlan_EncodeDecode(t *testing.T) {
+	plan := cachedStructPlan(reflect.TypeOf(planstruct{}), &client.options)
+	m, _, err := plan.encode(reflect.ValueOf(testPlanStruct), &client.options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := make([]interface{}, len(plan.keys))
+	for i, key := range plan.keys {
+		// the fast path must write the same as the generic one
+		want, err := toByte(reflect.ValueOf(testPlanStruct).FieldByIndex(plan.fields[i].index), &client.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := m[key].([]byte)
+		if string(got) != string(want) {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+		vals[i] = string(got)
+	}
+
+	var out planstruct
+	err = plan.decode(reflect.ValueOf(&out).Elem(), vals, &client.options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(testPlanStruct, out) {
+		t.Errorf("got %+v, want %+v", out, testPlanStruct)
+	}
+}
+
+func TestStructPlan_Project(t *testing.T) {
+	plan := cachedStructPlan(reflect.TypeOf(planstruct{}), &client.options)
+	if p, err := plan.project(&client.options); err != nil || p != plan {
+		t.Errorf("got %v, %v", p, err)
+	}
+
+	tests := []struct {
+		opts []Option
+		want []string
+	}{
+		{[]Option{Fields("k", "K1")}, []string{"k", "k_1"}},
+		{[]Option{Fields("Version", "ktrue")}, []string{"ktrue", "version"}},
+		{[]Option{Omit("k_8", "Ku", "CreatedAt", "updated_at")}, []string{"k", "k_1", "k_1_1", "ktrue", "version"}},
+		{[]Option{Fields("k", "k_1"), Omit("K")}, []string{"k_1"}},
+	}
+	for _, tt := range tests {
+		options := client.options
+		for _, opt := range tt.opts {
+			opt(&options)
+		}
+		p, err := plan.project(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.keys, tt.want) {
+			t.Errorf("got %v, want %v", p.keys, tt.want)
+		}
+	}
+
+	options := client.options
+	Fields("nope")(&options)
+	if _, err := plan.project(&options); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("got %v", err)
+	}
+}
+
+// go test -v -run=none -bench=^BenchmarkStruct -benchmem=true
+
+func BenchmarkStructEncodeUncached(b *testing.B) {
+	valValue := reflect.ValueOf(testPlanStruct)
+	for i := 0; i < b.N; i++ {
+		plan := newStructPlan(valValue.Type(), &client.options)
+		_, _, _ = plan.encode(valValue, &client.options)
+	}
+}
+
+func BenchmarkStructEncodeCached(b *testing.B) {
+	valValue := reflect.ValueOf(testPlanStruct)
+	for i := 0; i < b.N; i++ {
+		plan := cachedStructPlan(valValue.Type(), &client.options)
+		_, _, _ = plan.encode(valValue, &client.options)
+	}
+}
+
+func BenchmarkStructDecodeUncached(b *testing.B) {
+	vals := benchStructVals(b)
+	var out planstruct
+	valValue := reflect.ValueOf(&out).Elem()
+	for i := 0; i < b.N; i++ {
+		plan := newStructPlan(valValue.Type(), &client.options)
+		_ = plan.decode(valValue, vals, &client.options)
+	}
+}
+
+func BenchmarkStructDecodeCached(b *testing.B) {
+	vals := benchStructVals(b)
+	var out planstruct
+	valValue := reflect.ValueOf(&out).Elem()
+	for i := 0; i < b.N; i++ {
+		plan := cachedStructPlan(valValue.Type(), &client.options)
+		_ = plan.decode(valValue, vals, &client.options)
+	}
+}
+
+func benchStructVals(b *testing.B) []interface{} {
+	plan := cachedStructPlan(reflect.TypeOf(planstruct{}), &client.options)
+	m, _, err := plan.encode(reflect.ValueOf(testPlanStruct), &client.options)
+	if err != nil {
+		b.Fatal(err)
+	}
+	vals := make([]interface{}, len(plan.keys))
+	for i, key := range plan.keys {
+		vals[i] = string(m[key].([]byte))
+	}
+	return vals
+}
@@ -0,0 +1,156 @@
+package redis
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the redis representation of time.Time values.
+type TimeFormat uint8
+
+const (
+	// TimeRFC3339Nano writes times as time.RFC3339Nano strings, the default.
+	TimeRFC3339Nano TimeFormat = iota
+	// TimeUnix writes times as integer seconds since the epoch.
+	TimeUnix
+	// TimeUnixMilli writes times as integer milliseconds since the epoch.
+	TimeUnixMilli
+	// TimeUnixNano writes times as integer nanoseconds since the epoch.
+	TimeUnixNano
+)
+
+// DurationFormat is the redis representation of time.Duration values.
+type DurationFormat uint8
+
+const (
+	// DurationString writes durations as time.Duration.String(), the default.
+	DurationString DurationFormat = iota
+	// DurationNanos writes durations as integer nanoseconds.
+	DurationNanos
+)
+
+// timeTagOptions maps the time format tag options to their format.
+var timeTagOptions = map[string]TimeFormat{
+	"rfc3339nano": TimeRFC3339Nano,
+	"unix":        TimeUnix,
+	"unixmilli":   TimeUnixMilli,
+	"unixnano":    TimeUnixNano,
+}
+
+// durationTagOptions maps the duration format tag options to their format.
+var durationTagOptions = map[string]DurationFormat{
+	"text":  DurationString,
+	"nanos": DurationNanos,
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func formatTime(t time.Time, format TimeFormat) []byte {
+	switch format {
+	case TimeUnix:
+		return strconv.AppendInt(nil, t.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.AppendInt(nil, t.Unix()*1e3+int64(t.Nanosecond())/1e6, 10)
+	case TimeUnixNano:
+		return strconv.AppendInt(nil, t.UnixNano(), 10)
+	default:
+		return stringToBytes(t.Format(time.RFC3339Nano))
+	}
+}
+
+// parseTime reads both numbers and RFC3339 strings whatever the format,
+// numbers are in the unit of a unix format or else guessed from their magnitude.
+func parseTime(val string, format TimeFormat) (time.Time, error) {
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, val)
+	}
+	switch format {
+	case TimeUnix:
+		return time.Unix(n, 0), nil
+	case TimeUnixMilli:
+		return time.Unix(n/1e3, n%1e3*1e6), nil
+	case TimeUnixNano:
+		return time.Unix(0, n), nil
+	}
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs < 1e11:
+		return time.Unix(n, 0), nil
+	case abs < 1e14:
+		return time.Unix(n/1e3, n%1e3*1e6), nil
+	case abs < 1e17:
+		return time.Unix(n/1e6, n%1e6*1e3), nil
+	default:
+		return time.Unix(0, n), nil
+	}
+}
+
+func formatDuration(d time.Duration, format DurationFormat) []byte {
+	if format == DurationNanos {
+		return strconv.AppendInt(nil, int64(d), 10)
+	}
+	return stringToBytes(d.String())
+}
+
+// parseDuration reads both integer nanoseconds and time.Duration strings.
+func parseDuration(val string) (time.Duration, error) {
+	if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return time.Duration(n), nil
+	}
+	return time.ParseDuration(val)
+}
+
+// formatEncoder returns the encoder and decoder of time and duration fields
+// having a format tag option, ok is false for the other fields.
+func formatEncoder(fieldType reflect.Type, opts tagOptions) (encoder encoderFunc, decoder decoderFunc, ok bool) {
+	valType := fieldType
+	if valType.Kind() == reflect.Ptr {
+		valType = valType.Elem()
+	}
+	switch valType {
+	case timeType:
+		for name, format := range timeTagOptions {
+			if opts.Contains(name) {
+				return timeEncoder(format), timeDecoder(format), true
+			}
+		}
+	case durationType:
+		for name, format := range durationTagOptions {
+			if opts.Contains(name) {
+				return durationEncoder(format), setValueByString, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+func timeEncoder(format TimeFormat) encoderFunc {
+	return func(value reflect.Value, _ *Options) ([]byte, error) {
+		value = reflect.Indirect(value)
+		return formatTime(value.Interface().(time.Time), format), nil
+	}
+}
+
+func timeDecoder(format TimeFormat) decoderFunc {
+	return func(value reflect.Value, val string, _ *Options) error {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		return setTimeField(val, value, format)
+	}
+}
+
+func durationEncoder(format DurationFormat) encoderFunc {
+	return func(value reflect.Value, _ *Options) ([]byte, error) {
+		value = reflect.Indirect(value)
+		return formatDuration(time.Duration(value.Int()), format), nil
+	}
+}
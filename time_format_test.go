@@ -0,0 +1,141 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	tm := time.Date(2021, 8, 1, 12, 30, 45, 123456789, time.UTC)
+	tests := []struct {
+		format TimeFormat
+		want   string
+	}{
+		{TimeRFC3339Nano, "2021-08-01T12:30:45.123456789Z"},
+		{TimeUnix, "1627821045"},
+		{TimeUnixMilli, "1627821045123"},
+		{TimeUnixNano, "1627821045123456789"},
+	}
+	for _, tt := range tests {
+		got := string(formatTime(tm, tt.format))
+		if got != tt.want {
+			t.Errorf("formatTime(%d) = %q, want %q", tt.format, got, tt.want)
+		}
+		// numbers are read whatever the configured format
+		for _, format := range []TimeFormat{tt.format, TimeRFC3339Nano} {
+			parsed, err := parseTime(got, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tm
+			switch tt.format {
+			case TimeUnix:
+				want = tm.Truncate(time.Second)
+			case TimeUnixMilli:
+				want = tm.Truncate(time.Millisecond)
+			}
+			if !parsed.Equal(want) {
+				t.Errorf("parseTime(%q, %d) = %v, want %v", got, format, parsed, want)
+			}
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	d := 90 * time.Second
+	if got := string(formatDuration(d, DurationString)); got != "1m30s" {
+		t.Errorf("got %q", got)
+	}
+	if got := string(formatDuration(d, DurationNanos)); got != "90000000000" {
+		t.Errorf("got %q", got)
+	}
+	for _, val := range []string{"1m30s", "90000000000"} {
+		parsed, err := parseDuration(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != d {
+			t.Errorf("parseDuration(%q) = %v, want %v", val, parsed, d)
+		}
+	}
+}
+
+type formatstruct struct {
+	Kdefault  time.Time     `json:"kdefault"`
+	Kunix     time.Time     `json:"kunix,unix"`
+	Kmilli    *time.Time    `json:"kmilli,unixmilli"`
+	Krfc      time.Time     `json:"krfc,rfc3339nano"`
+	Kduration time.Duration `json:"kduration"`
+	Knanos    time.Duration `json:"knanos,nanos"`
+	Ktext     time.Duration `json:"ktext,text"`
+}
+
+func TestClient_TimeFormat(t *testing.T) {
+	tm := time.Date(2021, 8, 1, 12, 30, 45, 123456789, time.UTC)
+	in := formatstruct{
+		Kdefault:  tm,
+		Kunix:     tm,
+		Kmilli:    &tm,
+		Krfc:      tm,
+		Kduration: time.Second,
+		Knanos:    time.Second,
+		Ktext:     time.Second,
+	}
+	err := client.SetStructValue(context.Background(), "kformatstruct", in,
+		UseTimeFormat(TimeUnixNano), UseDurationFormat(DurationNanos))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := client.HGetAll(context.Background(), "kformatstruct").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"kdefault":  "1627821045123456789",
+		"kunix":     "1627821045",
+		"kmilli":    "1627821045123",
+		"krfc":      "2021-08-01T12:30:45.123456789Z",
+		"kduration": "1000000000",
+		"knanos":    "1000000000",
+		"ktext":     "1s",
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s = %q, want %q", k, m[k], v)
+		}
+	}
+
+	var out formatstruct
+	err = client.GetStructValue(context.Background(), "kformatstruct", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Kdefault.Equal(tm) || !out.Kunix.Equal(tm.Truncate(time.Second)) ||
+		out.Kmilli == nil || !out.Kmilli.Equal(tm.Truncate(time.Millisecond)) || !out.Krfc.Equal(tm) {
+		t.Errorf("got %+v", out)
+	}
+	if out.Kduration != time.Second || out.Knanos != time.Second || out.Ktext != time.Second {
+		t.Errorf("got %+v", out)
+	}
+
+	err = client.SetValue(context.Background(), "kformattime", tm, UseTimeFormat(TimeUnixMilli))
+	if err != nil {
+		t.Fatal(err)
+	}
+	str, err := client.Get(context.Background(), "kformattime").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str != "1627821045123" {
+		t.Errorf("got %q", str)
+	}
+	var ktime time.Time
+	err = client.GetValue(context.Background(), "kformattime", &ktime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ktime.Equal(tm.Truncate(time.Millisecond)) {
+		t.Errorf("got %v", ktime)
+	}
+}
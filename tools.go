@@ -2,38 +2,79 @@ package redis
 
 import (
 	"encoding"
+	"encoding/json"
 	"reflect"
 	"strings"
 	"time"
 	"unsafe"
 )
 
-func getStructKey(valType reflect.Type, i int, tag string) (key string) {
-	field := valType.Field(i)
-	if tag == "" {
-		return field.Name
+// redisTag takes precedence over Options.Tag when present on a field.
+const redisTag = "redis"
+
+// defaultTag holds the value of a field missing from the hash.
+const defaultTag = "default"
+
+// getStructKey returns the hash field name of a struct field, tagged reports
+// whether the name comes from the tag. The key is empty for skipped fields.
+func getStructKey(field reflect.StructField, tag string) (key string, opts tagOptions, tagged bool) {
+	tagName, ok := field.Tag.Lookup(redisTag)
+	if !ok && tag != "" {
+		tagName = field.Tag.Get(tag)
+	}
+	if tagName == "-" {
+		return "", "", false
+	}
+	if idx := strings.Index(tagName, ","); idx != -1 {
+		tagName, opts = tagName[:idx], tagOptions(tagName[idx+1:])
 	}
-	tagName := field.Tag.Get(tag)
-	if tagName == "" || tagName == "-" {
-		return ""
+	if tagName == "" {
+		return field.Name, opts, false
+	}
+	return tagName, opts, true
+}
+
+// tagOptions is the comma-separated list of options following the name in a struct tag.
+type tagOptions string
+
+// Contains reports whether the tag options contain the given option.
+func (o tagOptions) Contains(name string) bool {
+	s := string(o)
+	for s != "" {
+		var next string
+		if idx := strings.Index(s, ","); idx != -1 {
+			s, next = s[:idx], s[idx+1:]
+		}
+		if s == name {
+			return true
+		}
+		s = next
 	}
-	tagName = strings.Split(tagName, ",")[0]
-	return tagName
+	return false
 }
 
-func dotType2Byte(val interface{}) (ok bool, bs []byte) {
+func dotType2Byte(val interface{}, options *Options) (ok bool, bs []byte, err error) {
 	switch val := val.(type) {
 	case nil:
-		return ok, stringToBytes("")
+		return ok, stringToBytes(""), nil
+	case RedisMarshaler:
+		bs, err = val.MarshalRedis()
+		return true, bs, err
 	case time.Time:
-		return true, stringToBytes(val.Format(time.RFC3339Nano))
+		return true, formatTime(val, options.TimeFormat), nil
 	case time.Duration:
-		return true, stringToBytes(val.String())
+		return true, formatDuration(val, options.DurationFormat), nil
 	case encoding.BinaryMarshaler:
-		b, _ := val.MarshalBinary()
-		return true, b
+		bs, err = val.MarshalBinary()
+		return true, bs, err
+	case encoding.TextMarshaler:
+		bs, err = val.MarshalText()
+		return true, bs, err
+	case json.Marshaler:
+		bs, err = val.MarshalJSON()
+		return true, bs, err
 	}
-	return false, nil
+	return false, nil, nil
 }
 
 // stringToBytes converts string to byte slice without a memory allocation.
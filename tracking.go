@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+var errNotTracked = errors.New("redis: the snapshot holds no struct, read one with the Track option")
+
+// Snapshot is the state of a struct as read with the Track option, or as last saved.
+type Snapshot struct {
+	key   string
+	value reflect.Value
+	plan  *structPlan
+	// fields are the encoded values of the fields present in the hash.
+	fields  map[string]string
+	options Options
+}
+
+// take records the state of the struct read from key.
+func (s *Snapshot) take(key string, valValue reflect.Value, plan *structPlan, options Options) error {
+	m, _, err := plan.encode(valValue, &options)
+	if err != nil {
+		return err
+	}
+	fields := make(map[string]string, len(m))
+	for field, val := range m {
+		fields[field] = string(val.([]byte))
+	}
+	options.snapshot = nil
+	*s = Snapshot{key: key, value: valValue, plan: plan, fields: fields, options: options}
+	return nil
+}
+
+// Save writes the fields of the tracked struct changed since its snapshot: HSET for the
+// changed ones, HDEL for the nil and empty omitempty ones that were present.
+// Nothing is written when nothing changed, companion keys are left alone.
+// The options of the read apply, opts come on top.
+func (c *Client) Save(ctx context.Context, snapshot *Snapshot, opts ...Option) (err error) {
+	if snapshot == nil || snapshot.plan == nil {
+		return errNotTracked
+	}
+	options := snapshot.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	m, omitted, err := snapshot.plan.encode(snapshot.value, &options)
+	if err != nil {
+		return err
+	}
+	changed := make(map[string]interface{}, len(m))
+	for field, val := range m {
+		if old, ok := snapshot.fields[field]; !ok || old != string(val.([]byte)) {
+			changed[field] = val
+		}
+	}
+	var removed []string
+	for _, field := range omitted {
+		if _, ok := snapshot.fields[field]; ok {
+			removed = append(removed, field)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+	// the other fields of the hash are kept
+	options.WriteMode = Merge
+	res, err := c.writeKey(ctx, snapshot.key, options, keyWrite{kind: kindHash, args: hashArgs(changed), removed: removed})
+	if err != nil || !res.Written {
+		return err
+	}
+	for field, val := range changed {
+		snapshot.fields[field] = string(val.([]byte))
+	}
+	for _, field := range removed {
+		delete(snapshot.fields, field)
+	}
+	return nil
+}
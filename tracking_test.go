@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type trackedstruct struct {
+	Name  string  `json:"name"`
+	Email string  `json:"email,omitempty"`
+	Age   int     `json:"age"`
+	Nick  *string `json:"nick"`
+}
+
+func TestClient_Save(t *testing.T) {
+	ctx := context.Background()
+	key := "ktracked"
+	defer client.Del(ctx, key)
+
+	nick := "al"
+	in := trackedstruct{Name: "a", Email: "a@example.com", Age: 30, Nick: &nick}
+	if err := client.SetStructValue(ctx, key, in); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot Snapshot
+	var out trackedstruct
+	if err := client.GetStructValue(ctx, key, &out, Track(&snapshot)); err != nil {
+		t.Fatal(err)
+	}
+	// a concurrent edit of another field is kept
+	client.HSet(ctx, key, "age", 31)
+
+	out.Name = "b"
+	out.Email = ""
+	out.Nick = nil
+	if err := client.Save(ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hash, map[string]string{"name": "b", "age": "31"}) {
+		t.Errorf("got %v", hash)
+	}
+
+	// nothing changed since the last save
+	client.HSet(ctx, key, "name", "c")
+	if err = client.Save(ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := client.HGet(ctx, key, "name").Result(); name != "c" {
+		t.Errorf("got name %q", name)
+	}
+
+	out.Age = 40
+	if err = client.Save(ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if age, _ := client.HGet(ctx, key, "age").Result(); age != "40" {
+		t.Errorf("got age %q", age)
+	}
+}
+
+func TestClient_SaveProjection(t *testing.T) {
+	ctx := context.Background()
+	key := "ktrackedfields"
+	defer client.Del(ctx, key)
+
+	if err := client.SetStructValue(ctx, key, trackedstruct{Name: "a", Age: 30}); err != nil {
+		t.Fatal(err)
+	}
+	var snapshot Snapshot
+	var out trackedstruct
+	if err := client.GetStructValue(ctx, key, &out, Fields("name"), Track(&snapshot)); err != nil {
+		t.Fatal(err)
+	}
+	// fields outside of the projection are not saved
+	out.Name, out.Age = "b", 99
+	if err := client.Save(ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := client.HGetAll(ctx, key).Result()
+	if !reflect.DeepEqual(hash, map[string]string{"name": "b", "age": "30"}) {
+		t.Errorf("got %v", hash)
+	}
+
+	if err := client.Save(ctx, &Snapshot{}); !errors.Is(err, errNotTracked) {
+		t.Errorf("got %v", err)
+	}
+}
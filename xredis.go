@@ -8,14 +8,9 @@ import (
 
 // Deprecated: Use NewRedisClient instead.
 func NewClient(opt *Options) *Client {
-	if opt.Tag == "" {
-		opt.Tag = "json"
-	}
-	if opt.Expiration == 0 {
-		opt.Expiration = -1
-	}
+	opt.init()
 	client := redis.NewClient(&opt.Options)
-	return &Client{Cmdable: client, options: *opt}
+	return newClient(client, *opt)
 }
 
 func NewRedisClient(client redis.Cmdable, opts ...Option) *Client {
@@ -23,13 +18,14 @@ func NewRedisClient(client redis.Cmdable, opts ...Option) *Client {
 	for _, option := range opts {
 		option(opt)
 	}
-	if opt.Tag == "" {
-		opt.Tag = "json"
-	}
-	if opt.Expiration == 0 {
-		opt.Expiration = -1
-	}
-	return &Client{Cmdable: client, options: *opt}
+	opt.init()
+	return newClient(client, *opt)
+}
+
+func newClient(client redis.Cmdable, opt Options) *Client {
+	opt.converters = newConverterRegistry()
+	opt.migrations = newMigrationRegistry()
+	return &Client{Cmdable: client, options: opt}
 }
 
 type Client struct {
@@ -44,6 +40,75 @@ type Options struct {
 	Expiration  time.Duration
 	Start, Stop int64
 	SliceType
+	// Codec encodes maps, structs, slices and arrays, JSONCodec by default.
+	Codec Codec
+	// Flatten writes nested structs as "parent.child" hash fields,
+	// same as the ",flatten" tag option on a single field.
+	Flatten bool
+	// TimeFormat is the representation of time.Time values, TimeRFC3339Nano by default.
+	// Reads accept both numbers and RFC3339 strings.
+	TimeFormat TimeFormat
+	// DurationFormat is the representation of time.Duration values, DurationString by default.
+	DurationFormat DurationFormat
+	// WriteMode controls whether collection writes merge into or replace the existing key, Merge by default.
+	WriteMode WriteMode
+	// MinScore and MaxScore read the sorted set members within the scores,
+	// like the min and max of ZRANGEBYSCORE.
+	MinScore, MaxScore string
+	// ScanCount reads sets with SSCAN by batches of ScanCount members instead of SMEMBERS.
+	ScanCount int64
+	// WithScores sets the ",score" fields of the sorted set members on read.
+	WithScores bool
+	// ElementHashes stores the struct elements of slices as one hash each, the slice
+	// key holding the keys of the hashes. ElementKey is the template of these keys.
+	ElementHashes bool
+	ElementKey    string
+	// StreamMaxLen and StreamMinID trim streams on add, like the MAXLEN and MINID of XADD,
+	// StreamApprox trims with "~".
+	StreamMaxLen int64
+	StreamMinID  string
+	StreamApprox bool
+	// StreamReverse reads streams from the end with XREVRANGE.
+	StreamReverse bool
+	// Condition makes writes depend on the existence of the key.
+	Condition WriteCondition
+	// KeepTTL keeps the TTL of an existing key instead of setting Expiration.
+	KeepTTL bool
+	// Strict reports the hash fields without struct field and the missing ",required" fields on read.
+	Strict bool
+	// SchemaVersion is written with structs to the hidden "_version" hash field
+	// unless they implement SchemaVersioner, 0 writes no version.
+	SchemaVersion int
+	// WriteBackMigrations writes the migrated hashes back on read.
+	WriteBackMigrations bool
+	// Fields restricts the struct fields written and read to the named ones, by hash field
+	// or Go name, and Omit leaves the named ones out. Such partial writes always merge.
+	Fields []string
+	Omit   []string
+
+	converters *converterRegistry
+	migrations *migrationRegistry
+	// previous receives the value replaced by a write.
+	previous interface{}
+	// snapshot receives the state of the struct read.
+	snapshot *Snapshot
+}
+
+// partial reports whether only some of the struct fields are written and read.
+func (opt *Options) partial() bool {
+	return len(opt.Fields) > 0 || len(opt.Omit) > 0
+}
+
+func (opt *Options) init() {
+	if opt.Tag == "" {
+		opt.Tag = "json"
+	}
+	if opt.Expiration == 0 {
+		opt.Expiration = -1
+	}
+	if opt.Codec == nil {
+		opt.Codec = JSONCodec
+	}
 }
 
 type SliceType uint8
@@ -51,6 +116,33 @@ type SliceType uint8
 const (
 	List SliceType = iota
 	Set
+	// ZSet stores slices as sorted sets, see Scorer.
+	ZSet
+	// AutoDetect reads lists, sets and sorted sets alike, asking redis the TYPE of the key.
+	// Writes use lists.
+	AutoDetect
+)
+
+// WriteMode is how hashes, lists and sets are written over an existing key.
+type WriteMode uint8
+
+const (
+	// Merge adds to the existing key: lists are appended to, hash fields are overwritten one by one.
+	Merge WriteMode = iota
+	// Replace deletes the key and writes it again in a MULTI/EXEC transaction.
+	Replace
+)
+
+// WriteCondition is the precondition of a write on the existence of the key.
+type WriteCondition uint8
+
+const (
+	// WriteAlways writes whether the key exists or not, the default.
+	WriteAlways WriteCondition = iota
+	// WriteIfAbsent writes only when the key does not exist, like SET NX.
+	WriteIfAbsent
+	// WriteIfPresent writes only when the key exists, like SET XX.
+	WriteIfPresent
 )
 
 type Option func(opt *Options)
@@ -85,3 +177,167 @@ func RedisTypeSet() Option {
 		opt.SliceType = Set
 	}
 }
+
+func RedisTypeZSet() Option {
+	return func(opt *Options) {
+		opt.SliceType = ZSet
+	}
+}
+
+func RedisTypeAuto() Option {
+	return func(opt *Options) {
+		opt.SliceType = AutoDetect
+	}
+}
+
+func ScanCount(count int64) Option {
+	return func(opt *Options) {
+		opt.ScanCount = count
+	}
+}
+
+func ScoreRange(min, max string) Option {
+	return func(opt *Options) {
+		opt.MinScore = min
+		opt.MaxScore = max
+	}
+}
+
+func WithScores() Option {
+	return func(opt *Options) {
+		opt.WithScores = true
+	}
+}
+
+// ElementHashes stores the struct elements of slices as one hash each, their keys are
+// keyTemplate with "{key}" replaced by the slice key, "{pk}" by the ",pk" field
+// and "{index}" by the index of the element, "{key}:{pk}" when empty.
+// The slice key holds the keys of the hashes in a list, a set or a sorted set as per SliceType.
+// Writes that read the members first WATCH the slice key and retry when it changes, with the
+// clients that can't WATCH (the pipelines aside) they are not atomic.
+func ElementHashes(keyTemplate string) Option {
+	return func(opt *Options) {
+		opt.ElementHashes = true
+		opt.ElementKey = keyTemplate
+	}
+}
+
+func StreamMaxLen(maxLen int64) Option {
+	return func(opt *Options) {
+		opt.StreamMaxLen = maxLen
+	}
+}
+
+func StreamMinID(minID string) Option {
+	return func(opt *Options) {
+		opt.StreamMinID = minID
+	}
+}
+
+func StreamApprox() Option {
+	return func(opt *Options) {
+		opt.StreamApprox = true
+	}
+}
+
+func StreamReverse() Option {
+	return func(opt *Options) {
+		opt.StreamReverse = true
+	}
+}
+
+func UseCodec(codec Codec) Option {
+	return func(opt *Options) {
+		if codec != nil {
+			opt.Codec = codec
+		}
+	}
+}
+
+func Flatten() Option {
+	return func(opt *Options) {
+		opt.Flatten = true
+	}
+}
+
+func UseTimeFormat(format TimeFormat) Option {
+	return func(opt *Options) {
+		opt.TimeFormat = format
+	}
+}
+
+func UseDurationFormat(format DurationFormat) Option {
+	return func(opt *Options) {
+		opt.DurationFormat = format
+	}
+}
+
+func Strict() Option {
+	return func(opt *Options) {
+		opt.Strict = true
+	}
+}
+
+func UseSchemaVersion(version int) Option {
+	return func(opt *Options) {
+		opt.SchemaVersion = version
+	}
+}
+
+func WriteBackMigrations() Option {
+	return func(opt *Options) {
+		opt.WriteBackMigrations = true
+	}
+}
+
+func Fields(names ...string) Option {
+	return func(opt *Options) {
+		opt.Fields = append(opt.Fields[:len(opt.Fields):len(opt.Fields)], names...)
+	}
+}
+
+func Omit(names ...string) Option {
+	return func(opt *Options) {
+		opt.Omit = append(opt.Omit[:len(opt.Omit):len(opt.Omit)], names...)
+	}
+}
+
+func UseWriteMode(mode WriteMode) Option {
+	return func(opt *Options) {
+		opt.WriteMode = mode
+	}
+}
+
+func IfAbsent() Option {
+	return func(opt *Options) {
+		opt.Condition = WriteIfAbsent
+	}
+}
+
+func IfPresent() Option {
+	return func(opt *Options) {
+		opt.Condition = WriteIfPresent
+	}
+}
+
+func KeepTTL() Option {
+	return func(opt *Options) {
+		opt.KeepTTL = true
+	}
+}
+
+// GetPrevious decodes the value replaced by the write into previous, a pointer
+// like the value of GetValue. It's left alone when the key did not exist.
+func GetPrevious(previous interface{}) Option {
+	return func(opt *Options) {
+		opt.previous = previous
+	}
+}
+
+// Track records the state of the struct read into snapshot, for Save to write
+// only the fields changed since.
+func Track(snapshot *Snapshot) Option {
+	return func(opt *Options) {
+		opt.snapshot = snapshot
+	}
+}
@@ -0,0 +1,28 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+)
+
+// DelValue deletes the key of value, with the companion keys of structs.
+// value is only used for its type, a struct or a pointer to a struct.
+func (c *Client) DelValue(ctx context.Context, key string, value interface{}, opts ...Option) (err error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	keys := []string{key}
+	valType := reflect.TypeOf(value)
+	if valType != nil && valType.Kind() == reflect.Ptr {
+		valType = valType.Elem()
+	}
+	if valType != nil && valType.Kind() == reflect.Struct && valType != timeType && !isRedisScalar(valType, &options) {
+		plan := cachedStructPlan(valType, &options)
+		for i := range plan.companions {
+			keys = append(keys, companionKey(key, &plan.companions[i]))
+		}
+	}
+	return c.Del(ctx, keys...).Err()
+}
@@ -3,10 +3,15 @@ package redis
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
+var errPipelinedRead = errors.New("redis: pipelined reads can't be queued on a pipeline")
+
 func (c *Client) GetValue(ctx context.Context, key string, value interface{}, opts ...Option) (err error) {
 	options := c.options
 	for _, opt := range opts {
@@ -14,26 +19,23 @@ func (c *Client) GetValue(ctx context.Context, key string, value interface{}, op
 	}
 
 	valValue := reflect.ValueOf(value)
-	if valValue.Kind() != reflect.Ptr {
-		return errors.New("value CanSet returns false")
+	if valValue.Kind() != reflect.Ptr || valValue.IsNil() {
+		return ErrNotPointer
 	}
 	valValue = valValue.Elem()
+	if isRedisScalar(valValue.Type(), &options) {
+		return c.getSingleValue(ctx, key, valValue, options)
+	}
 	switch valValue.Kind() {
 	case reflect.Struct:
 		if _, ok := valValue.Interface().(time.Time); ok {
 			return c.getSingleValue(ctx, key, valValue, options)
 		}
 		return c.getStructValue(ctx, key, valValue, options)
-	case reflect.Array:
-		return c.getArrayValue(ctx, key, valValue, options)
-	case reflect.Slice:
-		return c.getSliceValue(ctx, key, valValue, options)
+	case reflect.Array, reflect.Slice:
+		return c.getSliceByType(ctx, key, valValue, options)
 	case reflect.Map:
-		switch val := value.(type) {
-		case *map[string]string, *map[string]interface{}:
-			return c.getMapValue(ctx, key, val, options)
-		}
-		return errors.New("value map is not map[string]string, map[string]interface{}")
+		return c.getMapValue(ctx, key, valValue, options)
 	default:
 		return c.getSingleValue(ctx, key, valValue, options)
 	}
@@ -49,6 +51,9 @@ func (c *Client) GetSingleValue(ctx context.Context, key string, value interface
 	if valValue.Kind() == reflect.Ptr {
 		valValue = valValue.Elem()
 	}
+	if !valValue.CanSet() {
+		return ErrNotPointer
+	}
 
 	return c.getSingleValue(ctx, key, valValue, options)
 }
@@ -65,12 +70,16 @@ func (c *Client) GetSliceValue(ctx context.Context, key string, value interface{
 	}
 
 	switch valValue.Kind() {
-	case reflect.Array:
-		return c.getArrayValue(ctx, key, valValue, options)
-	case reflect.Slice:
-		return c.getSliceValue(ctx, key, valValue, options)
+	case reflect.Array, reflect.Slice:
+		if !valValue.CanSet() {
+			return ErrNotPointer
+		}
+		if options.ElementHashes {
+			return c.getElementHashes(ctx, key, valValue, options)
+		}
+		return c.getSliceByType(ctx, key, valValue, options)
 	default:
-		return errors.New("value is not array or slice")
+		return unsupportedTypeError(valValue, "array or slice")
 	}
 }
 
@@ -81,8 +90,8 @@ func (c *Client) GetStructValue(ctx context.Context, key string, value interface
 	}
 
 	valValue := reflect.ValueOf(value)
-	if valValue.Kind() != reflect.Ptr {
-		return errors.New("value CanSet returns false")
+	if valValue.Kind() != reflect.Ptr || valValue.IsNil() {
+		return ErrNotPointer
 	}
 	valValue = valValue.Elem()
 
@@ -90,7 +99,7 @@ func (c *Client) GetStructValue(ctx context.Context, key string, value interface
 	case reflect.Struct:
 		return c.getStructValue(ctx, key, valValue, options)
 	default:
-		return errors.New("value is not struct")
+		return unsupportedTypeError(valValue, "struct")
 	}
 }
 
@@ -99,20 +108,29 @@ func (c *Client) GetMapValue(ctx context.Context, key string, value interface{},
 	for _, opt := range opts {
 		opt(&options)
 	}
-	switch val := value.(type) {
-	case map[string]string, map[string]interface{}, *map[string]string, *map[string]interface{}:
-		return c.getMapValue(ctx, key, val, options)
+
+	valValue := reflect.ValueOf(value)
+	if valValue.Kind() == reflect.Ptr {
+		valValue = valValue.Elem()
+	}
+
+	switch valValue.Kind() {
+	case reflect.Map:
+		if valValue.IsNil() && !valValue.CanSet() {
+			return ErrNotPointer
+		}
+		return c.getMapValue(ctx, key, valValue, options)
 	default:
-		return errors.New("value map is not map[string]string, map[string]interface{}")
+		return unsupportedTypeError(valValue, "map")
 	}
 }
 
 func (c *Client) getSingleValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
 	str, err := c.Get(ctx, key).Result()
 	if err != nil {
-		return err
+		return notFound(err)
 	}
-	return setValueByString(valValue, str)
+	return decodeError(setValueByString(valValue, str, &options), key)
 }
 
 func (c *Client) getSliceValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
@@ -123,7 +141,12 @@ func (c *Client) getSliceValue(ctx context.Context, key string, valValue reflect
 	if err != nil {
 		return err
 	}
-	return setSlice(strings, valValue)
+	if len(strings) == 0 {
+		if err = c.exists(ctx, key); err != nil {
+			return err
+		}
+	}
+	return decodeError(setSlice(strings, valValue, &options), key)
 }
 
 func (c *Client) getArrayValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
@@ -134,93 +157,193 @@ func (c *Client) getArrayValue(ctx context.Context, key string, valValue reflect
 	if err != nil {
 		return err
 	}
-	return setArray(strings, valValue)
+	if len(strings) == 0 {
+		if err = c.exists(ctx, key); err != nil {
+			return err
+		}
+	}
+	// a Range wider than the array gets as many elements as it can hold
+	if len(strings) > valValue.Len() {
+		strings = strings[:valValue.Len()]
+	}
+	return decodeError(setArray(strings, valValue, &options), key)
 }
 
-func (c *Client) getStructValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
-	fieldKeyIdxMap := make(map[string]int)
-	numField := valValue.NumField()
-	for i := 0; i < numField; i++ {
-		key := getStructKey(valValue.Type(), i, options.Tag)
-		if key == "" {
-			continue
+// getSliceByType reads a list, a set or a sorted set as per SliceType.
+func (c *Client) getSliceByType(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
+	sliceType := options.SliceType
+	if sliceType == AutoDetect {
+		keyType, err := c.Type(ctx, key).Result()
+		if err != nil {
+			return err
 		}
-		fieldKeyIdxMap[key] = i
+		switch keyType {
+		case "list":
+			sliceType = List
+		case "set":
+			sliceType = Set
+		case "zset":
+			sliceType = ZSet
+		case "none":
+			return ErrNotFound
+		default:
+			return fmt.Errorf("%w: key %q holds a %s", ErrUnsupportedType, key, keyType)
+		}
+	}
+	switch sliceType {
+	case Set:
+		return c.getSetValue(ctx, key, valValue, options)
+	case ZSet:
+		return c.getZSetValue(ctx, key, valValue, options)
 	}
-	fieldKeys := make([]string, 0, len(fieldKeyIdxMap))
-	for k := range fieldKeyIdxMap {
-		fieldKeys = append(fieldKeys, k)
+	if valValue.Kind() == reflect.Array {
+		return c.getArrayValue(ctx, key, valValue, options)
+	}
+	return c.getSliceValue(ctx, key, valValue, options)
+}
+
+// getSetValue reads the members of a set, in no particular order,
+// arrays get as many members as they can hold.
+func (c *Client) getSetValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
+	var members []string
+	if options.ScanCount > 0 {
+		// SSCAN may return a member more than once
+		seen := map[string]bool{}
+		iter := c.SScan(ctx, key, 0, "", options.ScanCount).Iterator()
+		for iter.Next(ctx) {
+			if member := iter.Val(); !seen[member] {
+				seen[member] = true
+				members = append(members, member)
+			}
+		}
+		err = iter.Err()
+	} else {
+		members, err = c.SMembers(ctx, key).Result()
 	}
-	fieldVals, err := c.HMGet(ctx, key, fieldKeys...).Result()
 	if err != nil {
 		return err
 	}
-	if len(fieldKeys) != len(fieldVals) {
-		return errors.New("HMGet should have the same number of keys and vals")
+	// redis deletes empty sets
+	if len(members) == 0 {
+		return ErrNotFound
 	}
-	for i := range fieldVals {
-		key := fieldKeys[i]
-		idx := fieldKeyIdxMap[key]
-		field := valValue.Field(idx)
-		valStr, ok := fieldVals[i].(string)
-		if !ok {
-			continue
-		}
-		err := setValueByString(field, valStr)
-		if err != nil {
-			return err
+	if valValue.Kind() == reflect.Array {
+		if len(members) > valValue.Len() {
+			members = members[:valValue.Len()]
 		}
+		return decodeError(setArray(members, valValue, &options), key)
 	}
-	return nil
+	return decodeError(setSlice(members, valValue, &options), key)
 }
 
-func (c *Client) getMapValueSring(ctx context.Context, key string, val map[string]string, options Options) (err error) {
-	stringStringMap, err := c.HGetAll(ctx, key).Result()
+// getStructValue reads the hash of a struct, in one pipeline with its companion keys if any.
+func (c *Client) getStructValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
+	plan, err := cachedStructPlan(valValue.Type(), &options).project(&options)
 	if err != nil {
 		return err
 	}
-	for k, v := range stringStringMap {
-		val[k] = v
+	migrations := options.migrations.lookup(valValue.Type())
+	byHash := options.Strict || len(migrations) > 0
+	var hashCmd *redis.StringStringMapCmd
+	var fieldsCmd *redis.SliceCmd
+	var companionCmds []redis.Cmder
+	read := func(pipe redis.Cmdable) {
+		if byHash {
+			hashCmd = pipe.HGetAll(ctx, key)
+		} else if len(plan.keys) > 0 {
+			fieldsCmd = pipe.HMGet(ctx, key, plan.keys...)
+		}
+		companionCmds = readCompanions(ctx, pipe, key, plan)
+	}
+	if len(plan.companions) == 0 {
+		read(c.Cmdable)
+	} else if err = c.pipelined(ctx, read); err != nil {
+		return err
 	}
-	return nil
-}
+	found, errs := decodeCompanions(valValue, plan, companionCmds, &options)
 
-func (c *Client) getMapValue(ctx context.Context, key string, val interface{}, options Options) (err error) {
-	switch val := val.(type) {
-	case map[string]string:
-		stringStringMap, err := c.HGetAll(ctx, key).Result()
+	if byHash {
+		hash, err := hashCmd.Result()
 		if err != nil {
 			return err
 		}
-		for k, v := range stringStringMap {
-			val[k] = v
+		if len(hash) == 0 && !found {
+			return ErrNotFound
 		}
-	case map[string]interface{}:
-		stringStringMap, err := c.HGetAll(ctx, key).Result()
-		if err != nil {
-			return err
+		if len(hash) > 0 && len(migrations) > 0 {
+			err = c.migrateHash(ctx, key, hash, migrations, schemaVersion(valValue, &options), options)
+			if err != nil {
+				return err
+			}
 		}
-		for k, v := range stringStringMap {
-			val[k] = v
+		errs = appendErrors(errs, plan.decodeHash(valValue, hash, &options))
+	} else {
+		var fieldVals []interface{}
+		if fieldsCmd != nil {
+			fieldVals, err = fieldsCmd.Result()
+			if err != nil {
+				return err
+			}
 		}
-	case *map[string]string:
-		stringStringMap, err := c.HGetAll(ctx, key).Result()
-		if err != nil {
-			return err
+		if allNil(fieldVals) && !found {
+			// a hash without any of the fields, or no hash at all
+			if err = c.exists(ctx, key); err != nil {
+				return err
+			}
 		}
-		for k, v := range stringStringMap {
-			(*(val))[k] = v
-		}
-	case *map[string]interface{}:
-		stringStringMap, err := c.HGetAll(ctx, key).Result()
-		if err != nil {
+		errs = appendErrors(errs, plan.decode(valValue, fieldVals, &options))
+	}
+	applyDefaults(valValue)
+	if options.snapshot != nil {
+		if err = options.snapshot.take(key, valValue, plan, options); err != nil {
 			return err
 		}
-		for k, v := range stringStringMap {
-			(*(val))[k] = v
-		}
-	default:
-		return errors.New("value map is not map[string]string, map[string]interface{}")
+	}
+	return decodeError(errs.err(), key)
+}
+
+func (c *Client) getMapValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
+	stringStringMap, err := c.HGetAll(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	// redis deletes empty hashes
+	if len(stringStringMap) == 0 {
+		return ErrNotFound
+	}
+	return decodeError(setMap(stringStringMap, valValue, &options), key)
+}
+
+// pipelined runs the reads queued by fn in one round trip. Clients over a pipeline
+// can't, the replies would only come once its owner runs it.
+func (c *Client) pipelined(ctx context.Context, fn func(pipe redis.Cmdable)) error {
+	if _, ok := c.Cmdable.(redis.Pipeliner); ok {
+		return errPipelinedRead
+	}
+	_, err := c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		fn(pipe)
+		return nil
+	})
+	return err
+}
+
+// exists returns ErrNotFound when the key does not exist.
+func (c *Client) exists(ctx context.Context, key string) error {
+	n, err := c.Exists(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
+
+func allNil(vals []interface{}) bool {
+	for _, val := range vals {
+		if val != nil {
+			return false
+		}
+	}
+	return true
+}
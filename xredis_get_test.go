@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestClient_GetSliceValueSet(t *testing.T) {
+	ctx := context.Background()
+	key := "kgetset"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	in := make([]int, 100)
+	for i := range in {
+		in[i] = i
+	}
+	err := client.SetSliceValue(ctx, key, in, RedisTypeSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range [][]Option{
+		{RedisTypeSet()},
+		{RedisTypeSet(), ScanCount(10)},
+		{RedisTypeAuto()},
+	} {
+		var out []int
+		err = client.GetSliceValue(ctx, key, &out, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Ints(out)
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("got %v, want %v", out, in)
+		}
+	}
+
+	// GetValue honours the slice type too
+	var got []int
+	if err = client.GetValue(ctx, key, &got, RedisTypeSet()); err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("got %v, want %v", got, in)
+	}
+
+	var array [3]int
+	err = client.GetSliceValue(ctx, key, &array, RedisTypeSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range array {
+		if v < 0 || v >= 100 {
+			t.Errorf("got %v", array)
+		}
+	}
+
+	client.Del(ctx, key)
+	var out []int
+	if err = client.GetSliceValue(ctx, key, &out, RedisTypeSet()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestClient_GetSliceValueAuto(t *testing.T) {
+	ctx := context.Background()
+	keys := []string{"kautolist", "kautozset", "kautostring", "kautonone"}
+	client.Del(ctx, keys...)
+	defer client.Del(ctx, keys...)
+	want := []string{"a", "b", "c"}
+	err := client.SetSliceValue(ctx, "kautolist", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.SetSliceValue(ctx, "kautozset", want, RedisTypeZSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys[:2] {
+		var out []string
+		err = client.GetSliceValue(ctx, key, &out, RedisTypeAuto())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("%s: got %v, want %v", key, out, want)
+		}
+		// GetValue detects the type too
+		out = nil
+		err = client.GetValue(ctx, key, &out, RedisTypeAuto())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("%s: got %v, want %v", key, out, want)
+		}
+	}
+
+	client.Set(ctx, "kautostring", strconv.Itoa(1), 0)
+	var out []string
+	if err = client.GetSliceValue(ctx, "kautostring", &out, RedisTypeAuto()); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+	if err = client.GetSliceValue(ctx, "kautonone", &out, RedisTypeAuto()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestClient_SetGetValueSliceTypes(t *testing.T) {
+	ctx := context.Background()
+	key := "ksetgetslicetypes"
+	defer client.Del(ctx, key)
+
+	want := []string{"a", "b", "c"}
+	for _, sliceType := range []SliceType{List, Set, ZSet, AutoDetect} {
+		client.Del(ctx, key)
+		c := NewRedisClient(client.Cmdable, func(opt *Options) { opt.SliceType = sliceType })
+		if err := c.SetValue(ctx, key, want); err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		if err := c.GetValue(ctx, key, &out); err != nil {
+			t.Fatalf("%v: %v", sliceType, err)
+		}
+		sort.Strings(out)
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("%v: got %v, want %v", sliceType, out, want)
+		}
+	}
+
+	// an array gets as many elements as it can hold
+	client.Del(ctx, key)
+	client.RPush(ctx, key, 1, 2, 3)
+	var arr [2]int
+	if err := client.GetValue(ctx, key, &arr, Range(0, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if arr != [2]int{1, 2} {
+		t.Errorf("got %v", arr)
+	}
+}
+
+func TestClient_PartialRead(t *testing.T) {
+	ctx := context.Background()
+	key := "kpartialread"
+	defer client.DelValue(ctx, key, partialstruct{})
+
+	in := partialstruct{Name: "a", Email: "a@example.com", Age: 30, Tags: []string{"x"}}
+	if err := client.SetStructValue(ctx, key, in); err != nil {
+		t.Fatal(err)
+	}
+	out := partialstruct{Name: "kept", Tags: []string{"kept"}}
+	if err := client.GetStructValue(ctx, key, &out, Fields("Age", "email")); err != nil {
+		t.Fatal(err)
+	}
+	want := partialstruct{Name: "kept", Email: "a@example.com", Age: 30, Tags: []string{"kept"}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+
+	out = partialstruct{Age: -1}
+	if err := client.GetStructValue(ctx, key, &out, Omit("age"), Strict()); err != nil {
+		t.Fatal(err)
+	}
+	want = partialstruct{Name: "a", Email: "a@example.com", Age: -1, Tags: []string{"x"}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+
+	if err := client.GetStructValue(ctx, key, &out, Fields("missing")); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("got %v", err)
+	}
+}
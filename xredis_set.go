@@ -2,13 +2,21 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"reflect"
+	"strconv"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 func (c *Client) SetValue(ctx context.Context, key string, value interface{}, opts ...Option) (err error) {
+	_, err = c.SetValueResult(ctx, key, value, opts...)
+	return err
+}
+
+// SetValueResult is SetValue telling whether the write happened,
+// which depends on the IfAbsent and IfPresent options.
+func (c *Client) SetValueResult(ctx context.Context, key string, value interface{}, opts ...Option) (res SetResult, err error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
@@ -18,21 +26,30 @@ func (c *Client) SetValue(ctx context.Context, key string, value interface{}, op
 	if valValue.Kind() == reflect.Ptr {
 		valValue = valValue.Elem()
 	}
+	if !valValue.IsValid() {
+		return res, unsupportedTypeError(valValue, "non-nil value")
+	}
+	if isRedisScalar(valValue.Type(), &options) {
+		return c.setSingleValue(ctx, key, valValue, options)
+	}
 	switch valValue.Kind() {
 	case reflect.Map:
 		return c.setMapValue(ctx, key, valValue, options)
 	case reflect.Struct:
-		if val, ok := valValue.Interface().(time.Time); ok {
-			bytes := stringToBytes(val.Format(time.RFC3339Nano))
-			return c.Set(ctx, key, bytes, options.Expiration).Err()
+		if _, ok := valValue.Interface().(time.Time); ok {
+			return c.setSingleValue(ctx, key, valValue, options)
 		}
 		return c.setStructValue(ctx, key, valValue, options)
 	case reflect.Array, reflect.Slice:
-		return c.setListValue(ctx, key, valValue, options)
-	default:
-		if ok, bytes := dotType2Byte(value); ok {
-			return c.Set(ctx, key, bytes, options.Expiration).Err()
+		switch options.SliceType {
+		case Set:
+			return c.setSetValue(ctx, key, valValue, options)
+		case ZSet:
+			return c.setZSetValue(ctx, key, valValue, options)
+		default: // List, AutoDetect
+			return c.setListValue(ctx, key, valValue, options)
 		}
+	default:
 		return c.setSingleValue(ctx, key, valValue, options)
 	}
 }
@@ -43,16 +60,16 @@ func (c *Client) SetSingleValue(ctx context.Context, key string, value interface
 		opt(&options)
 	}
 
-	if ok, bytes := dotType2Byte(value); ok {
-		return c.Set(ctx, key, bytes, options.Expiration).Err()
-	}
-
 	valValue := reflect.ValueOf(value)
 	if valValue.Kind() == reflect.Ptr {
 		valValue = valValue.Elem()
 	}
+	if !valValue.IsValid() {
+		return unsupportedTypeError(valValue, "non-nil value")
+	}
 
-	return c.setSingleValue(ctx, key, valValue, options)
+	_, err = c.setSingleValue(ctx, key, valValue, options)
+	return err
 }
 
 func (c *Client) SetSliceValue(ctx context.Context, key string, value interface{}, opts ...Option) (err error) {
@@ -68,14 +85,19 @@ func (c *Client) SetSliceValue(ctx context.Context, key string, value interface{
 
 	switch valValue.Kind() {
 	case reflect.Array, reflect.Slice:
-		switch options.SliceType {
-		case List:
-			return c.setListValue(ctx, key, valValue, options)
-		default: // Set
-			return c.setSetValue(ctx, key, valValue, options)
+		switch {
+		case options.ElementHashes:
+			_, err = c.setElementHashes(ctx, key, valValue, options)
+		case options.SliceType == Set:
+			_, err = c.setSetValue(ctx, key, valValue, options)
+		case options.SliceType == ZSet:
+			_, err = c.setZSetValue(ctx, key, valValue, options)
+		default: // List, AutoDetect
+			_, err = c.setListValue(ctx, key, valValue, options)
 		}
+		return err
 	default:
-		return errors.New("value is not array or slice")
+		return unsupportedTypeError(valValue, "array or slice")
 	}
 }
 
@@ -92,9 +114,10 @@ func (c *Client) SetStructValue(ctx context.Context, key string, value interface
 
 	switch valValue.Kind() {
 	case reflect.Struct:
-		return c.setStructValue(ctx, key, valValue, options)
+		_, err = c.setStructValue(ctx, key, valValue, options)
+		return err
 	default:
-		return errors.New("value is not struct")
+		return unsupportedTypeError(valValue, "struct")
 	}
 }
 
@@ -111,116 +134,205 @@ func (c *Client) SetMapValue(ctx context.Context, key string, value interface{},
 
 	switch valValue.Kind() {
 	case reflect.Map:
-		return c.setMapValue(ctx, key, valValue, options)
+		_, err = c.setMapValue(ctx, key, valValue, options)
+		return err
 	default:
-		return errors.New("value is not map")
+		return unsupportedTypeError(valValue, "map")
 	}
 }
 
-func (c *Client) setSingleValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
-	switch valValue.Kind() {
-	case reflect.Map, reflect.Struct, reflect.Array, reflect.Slice:
-		bytes, err := json.Marshal(valValue.Interface())
-		if err != nil {
-			return err
-		}
-		return c.Set(ctx, key, bytes, options.Expiration).Err()
-	default:
-		bytes := toByte(valValue)
-		return c.Set(ctx, key, bytes, options.Expiration).Err()
+func (c *Client) setSingleValue(ctx context.Context, key string, valValue reflect.Value, options Options) (res SetResult, err error) {
+	bytes, err := toByte(valValue, &options)
+	if err != nil {
+		return res, err
+	}
+	if options.conditional() {
+		return c.setStringCond(ctx, key, bytes, options)
 	}
+	return SetResult{Written: true}, c.Set(ctx, key, bytes, options.Expiration).Err()
 }
 
-func (c *Client) setListValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
-	valLen := valValue.Len()
-	if valLen == 0 {
-		return nil
+func (c *Client) setListValue(ctx context.Context, key string, valValue reflect.Value, options Options) (res SetResult, err error) {
+	vals, err := sliceValues(valValue, &options)
+	if err != nil {
+		return res, err
 	}
-	vals := make([]interface{}, valLen)
-	for i := 0; i < valLen; i++ {
-		sliceVal := valValue.Index(i)
-		vals[i] = toByte(sliceVal)
+	if len(vals) == 0 && options.WriteMode != Replace {
+		return res, nil
 	}
-	err = c.RPush(ctx, key, vals).Err()
+	return c.writeKey(ctx, key, options, keyWrite{kind: kindList, args: vals})
+}
+
+func (c *Client) setSetValue(ctx context.Context, key string, valValue reflect.Value, options Options) (res SetResult, err error) {
+	vals, err := sliceValues(valValue, &options)
 	if err != nil {
-		return err
+		return res, err
 	}
-	err = c.expireKeyTTl(ctx, key, options.Expiration)
-	if err != nil {
-		return err
+	if len(vals) == 0 && options.WriteMode != Replace {
+		return res, nil
 	}
-	return nil
+	return c.writeKey(ctx, key, options, keyWrite{kind: kindSet, args: vals})
 }
 
-func (c *Client) setSetValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
+func sliceValues(valValue reflect.Value, options *Options) (vals []interface{}, err error) {
 	valLen := valValue.Len()
-	if valLen == 0 {
-		return nil
-	}
-	vals := make([]interface{}, valLen)
+	vals = make([]interface{}, valLen)
 	for i := 0; i < valLen; i++ {
 		sliceVal := valValue.Index(i)
-		vals[i] = toByte(sliceVal)
+		vals[i], err = toByte(sliceVal, options)
+		if err != nil {
+			return nil, err
+		}
 	}
-	err = c.SAdd(ctx, key, vals).Err()
+	return vals, nil
+}
+
+func (c *Client) setStructValue(ctx context.Context, key string, valValue reflect.Value, options Options) (res SetResult, err error) {
+	if options.partial() {
+		// the fields left out are kept
+		options.WriteMode = Merge
+	}
+	plan, err := cachedStructPlan(valValue.Type(), &options).project(&options)
 	if err != nil {
-		return err
+		return res, err
 	}
-	err = c.expireKeyTTl(ctx, key, options.Expiration)
+	w, err := structWrite(valValue, plan, &options)
 	if err != nil {
-		return err
+		return res, err
+	}
+	if len(plan.companions) > 0 {
+		return c.writeWithCompanions(ctx, key, valValue, plan, options, w)
+	}
+	if len(w.args) == 0 && len(w.removed) == 0 && options.WriteMode != Replace {
+		return res, nil
 	}
-	return nil
+	return c.writeKey(ctx, key, options, w)
 }
 
-func (c *Client) setStructValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
-	numField := valValue.NumField()
-	m := make(map[string]interface{}, numField)
-	for i := 0; i < numField; i++ {
-		valType := valValue.Type()
-		key := getStructKey(valType, i, options.Tag)
-		m[key] = toByte(valValue.Field(i))
+// structWrite returns the hash write of the fields of plan.
+func structWrite(valValue reflect.Value, plan *structPlan, options *Options) (w keyWrite, err error) {
+	// nil and empty omitempty fields are removed, so that the hash mirrors the struct
+	m, omitted, err := plan.encode(valValue, options)
+	if err != nil {
+		return w, err
 	}
-	if len(m) == 0 {
-		return nil
+	// partial writes leave the hash at its version
+	if version := schemaVersion(valValue, options); version > 0 && !options.partial() {
+		m[versionField] = strconv.Itoa(version)
 	}
-	err = c.HSet(ctx, key, m).Err()
-	if err != nil {
-		return err
+	if options.WriteMode == Replace {
+		// the hash is deleted first, so there is nothing to remove
+		omitted = nil
 	}
-	err = c.expireKeyTTl(ctx, key, options.Expiration)
+	return keyWrite{kind: kindHash, args: hashArgs(m), removed: omitted}, nil
+}
+
+func (c *Client) setMapValue(ctx context.Context, key string, valValue reflect.Value, options Options) (res SetResult, err error) {
+	m, err := mapValues(valValue, &options)
 	if err != nil {
-		return err
+		return res, err
 	}
-	return nil
+	if len(m) == 0 && options.WriteMode != Replace {
+		return res, nil
+	}
+	return c.writeKey(ctx, key, options, keyWrite{kind: kindHash, args: hashArgs(m)})
 }
 
-func (c *Client) setMapValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
-	m := map[string]interface{}{}
+// mapValues returns the hash field values of a map.
+func mapValues(valValue reflect.Value, options *Options) (m map[string]interface{}, err error) {
+	m = make(map[string]interface{}, valValue.Len())
 	iter := valValue.MapRange()
 	for iter.Next() {
-		k := iter.Key()
-		v := iter.Value()
-		m[toString(k)] = toByte(v)
+		mk, err := toByte(iter.Key(), options)
+		if err != nil {
+			return nil, err
+		}
+		m[bytesToString(mk)], err = toByte(iter.Value(), options)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if len(m) == 0 {
-		return nil
+	return m, nil
+}
+
+// hashArgs returns the field value pairs of HSET.
+func hashArgs(m map[string]interface{}) []interface{} {
+	args := make([]interface{}, 0, 2*len(m))
+	for field, val := range m {
+		args = append(args, field, val)
 	}
-	err = c.HSet(ctx, key, m).Err()
-	if err != nil {
-		return err
+	return args
+}
+
+const (
+	kindHash = "hash"
+	kindList = "list"
+	kindSet  = "set"
+	kindZSet = "zset"
+)
+
+// keyWrite is the data written to a collection key.
+type keyWrite struct {
+	// kind is kindHash, kindList, kindSet or kindZSet.
+	kind string
+	// args are the HSET field value pairs, the RPUSH or SADD members, or the ZADD score member pairs.
+	args []interface{}
+	// removed are the hash fields to HDEL.
+	removed []string
+}
+
+func (w *keyWrite) queue(ctx context.Context, pipe redis.Pipeliner, key string) {
+	if len(w.args) > 0 {
+		switch w.kind {
+		case kindHash:
+			pipe.HSet(ctx, key, w.args...)
+		case kindList:
+			pipe.RPush(ctx, key, w.args...)
+		case kindSet:
+			pipe.SAdd(ctx, key, w.args...)
+		case kindZSet:
+			members := make([]*redis.Z, 0, len(w.args)/2)
+			for i := 0; i+1 < len(w.args); i += 2 {
+				members = append(members, &redis.Z{Score: w.args[i].(float64), Member: w.args[i+1]})
+			}
+			pipe.ZAdd(ctx, key, members...)
+		}
 	}
-	err = c.expireKeyTTl(ctx, key, options.Expiration)
-	if err != nil {
-		return err
+	if len(w.removed) > 0 {
+		pipe.HDel(ctx, key, w.removed...)
 	}
-	return nil
 }
 
-// 设置有效过期时长
-func (c *Client) expireKeyTTl(ctx context.Context, key string, expiration time.Duration) error {
-	if expiration > 0 {
-		return c.Expire(ctx, key, expiration).Err()
+// queueKey queues the write of a key with its TTL, in Replace mode the key is deleted first.
+func (w *keyWrite) queueKey(ctx context.Context, pipe redis.Pipeliner, key string, options *Options) {
+	if options.WriteMode == Replace {
+		pipe.Del(ctx, key)
+	}
+	w.queue(ctx, pipe, key)
+	if options.Expiration > 0 {
+		pipe.Expire(ctx, key, options.Expiration)
 	}
-	return nil
+}
+
+// writeKey writes a collection key and sets its TTL in one MULTI/EXEC transaction,
+// or in a script for conditional writes. In Replace mode the key is deleted first.
+func (c *Client) writeKey(ctx context.Context, key string, options Options, w keyWrite) (res SetResult, err error) {
+	if options.conditional() {
+		return c.writeKeyCond(ctx, key, options, w)
+	}
+	err = c.txPipelined(ctx, func(pipe redis.Pipeliner) error {
+		w.queueKey(ctx, pipe, key, &options)
+		return nil
+	})
+	return SetResult{Written: err == nil}, err
+}
+
+// txPipelined runs fn in a MULTI/EXEC transaction. When the client wraps a pipeline
+// the commands are queued on it instead, to be run by its owner.
+func (c *Client) txPipelined(ctx context.Context, fn func(pipe redis.Pipeliner) error) error {
+	if pipe, ok := c.Cmdable.(redis.Pipeliner); ok {
+		return fn(pipe)
+	}
+	_, err := c.TxPipelined(ctx, fn)
+	return err
 }
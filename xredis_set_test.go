@@ -0,0 +1,193 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestClient_WriteModeReplace(t *testing.T) {
+	ctx := context.Background()
+	c := NewRedisClient(client.Cmdable, Expiration(time.Minute), UseWriteMode(Replace))
+
+	key := "kreplacelist"
+	c.Del(ctx, key)
+	defer c.Del(ctx, key)
+	for i := 0; i < 2; i++ {
+		err := c.SetValue(ctx, key, []string{"a", "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var list []string
+	err := c.GetValue(ctx, key, &list, Range(0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(list, []string{"a", "b"}) {
+		t.Errorf("got %v", list)
+	}
+	if ttl, _ := c.TTL(ctx, key).Result(); ttl <= 0 {
+		t.Errorf("ttl = %v, want > 0", ttl)
+	}
+	// merge appends
+	err = c.SetValue(ctx, key, []string{"c"}, UseWriteMode(Merge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.LLen(ctx, key).Result(); n != 3 {
+		t.Errorf("len = %d, want 3", n)
+	}
+
+	key = "kreplaceset"
+	c.SAdd(ctx, key, "x")
+	defer c.Del(ctx, key)
+	err = c.SetSliceValue(ctx, key, []string{"a", "b"}, RedisTypeSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, _ := c.SMembers(ctx, key).Result()
+	sort.Strings(members)
+	if !reflect.DeepEqual(members, []string{"a", "b"}) {
+		t.Errorf("got %v", members)
+	}
+
+	key = "kreplacemap"
+	c.HSet(ctx, key, "stale", "v")
+	defer c.Del(ctx, key)
+	err = c.SetMapValue(ctx, key, map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := c.HGetAll(ctx, key).Result()
+	if !reflect.DeepEqual(m, map[string]string{"a": "1"}) {
+		t.Errorf("got %v", m)
+	}
+
+	key = "kreplacestruct"
+	c.HSet(ctx, key, "stale", "v")
+	defer c.Del(ctx, key)
+	err = c.SetStructValue(ctx, key, tagstruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.HExists(ctx, key, "stale").Result(); ok {
+		t.Error("stale field should be removed")
+	}
+
+	// replacing with nothing deletes the key
+	err = c.SetMapValue(ctx, key, map[string]int{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.GetMapValue(ctx, key, &m); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestClient_WriteWithTTL(t *testing.T) {
+	ctx := context.Background()
+	c := NewRedisClient(client.Cmdable, Expiration(time.Minute))
+	values := map[string]interface{}{
+		"kttllist":   []int{1, 2},
+		"kttlmap":    map[string]int{"a": 1},
+		"kttlstruct": subkstruct{K: "v"},
+	}
+	for key, value := range values {
+		c.Del(ctx, key)
+		err := c.SetValue(ctx, key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ttl, _ := c.TTL(ctx, key).Result(); ttl <= 0 {
+			t.Errorf("%s: ttl = %v, want > 0", key, ttl)
+		}
+		c.Del(ctx, key)
+	}
+	err := c.SetSliceValue(ctx, "kttlset", []int{1, 2}, RedisTypeSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Del(ctx, "kttlset")
+	if ttl, _ := c.TTL(ctx, "kttlset").Result(); ttl <= 0 {
+		t.Errorf("ttl = %v, want > 0", ttl)
+	}
+}
+
+func TestClient_WritePipeline(t *testing.T) {
+	ctx := context.Background()
+	key := "kpipelinestruct"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	// a client over a pipeline queues the commands for the owner of the pipeline
+	pipe := client.TxPipeline()
+	c := NewRedisClient(pipe, Expiration(time.Minute), UseWriteMode(Replace))
+	err := c.SetStructValue(ctx, key, subkstruct{K: "v", K1: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("commands should wait for Exec")
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out subkstruct
+	err = client.GetStructValue(ctx, key, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.K != "v" || out.K1 != 1 {
+		t.Errorf("got %+v", out)
+	}
+	if ttl, _ := client.TTL(ctx, key).Result(); ttl <= 0 {
+		t.Errorf("ttl = %v, want > 0", ttl)
+	}
+}
+
+type partialstruct struct {
+	Name  string   `json:"name"`
+	Email string   `json:"email,omitempty"`
+	Age   int      `json:"age"`
+	Tags  []string `json:"tags,list"`
+}
+
+func TestClient_PartialWrite(t *testing.T) {
+	ctx := context.Background()
+	key := "kpartial"
+	defer client.DelValue(ctx, key, partialstruct{})
+
+	in := partialstruct{Name: "a", Email: "a@example.com", Age: 30, Tags: []string{"x"}}
+	if err := client.SetStructValue(ctx, key, in); err != nil {
+		t.Fatal(err)
+	}
+	// only the name and the now empty email are written, even in Replace mode
+	update := partialstruct{Name: "b", Age: 99}
+	err := client.SetStructValue(ctx, key, update, Fields("name", "Email"), UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hash, map[string]string{"name": "b", "age": "30"}) {
+		t.Errorf("got %v", hash)
+	}
+	if tags, _ := client.LRange(ctx, key+":tags", 0, -1).Result(); !reflect.DeepEqual(tags, []string{"x"}) {
+		t.Errorf("got tags %v", tags)
+	}
+
+	err = client.SetStructValue(ctx, key, partialstruct{Age: 31}, Omit("name", "email", "tags"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if age, _ := client.HGet(ctx, key, "age").Result(); age != "31" {
+		t.Errorf("got age %q", age)
+	}
+}
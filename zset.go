@@ -0,0 +1,193 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Scorer is implemented by the elements of slices stored as sorted sets
+// that compute their own score. A ",score" tagged field takes precedence,
+// elements with neither are scored by their index.
+type Scorer interface {
+	Score() float64
+}
+
+var scorerType = reflect.TypeOf((*Scorer)(nil)).Elem()
+
+// scoreField returns the ",score" tagged field of the struct elements of a slice.
+func scoreField(elemType reflect.Type, options *Options) (field *structField, ok bool) {
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct || elemType == timeType || isRedisScalar(elemType, options) {
+		return nil, false
+	}
+	return cachedStructPlan(elemType, options).fieldBy(isScore)
+}
+
+// zsetValues returns the ZADD score member pairs of a slice. The score comes from
+// the ",score" field, which is zeroed in the member, the Score method or the index.
+func zsetValues(valValue reflect.Value, options *Options) (vals []interface{}, err error) {
+	valLen := valValue.Len()
+	field, _ := scoreField(valValue.Type().Elem(), options)
+	vals = make([]interface{}, 0, 2*valLen)
+	for i := 0; i < valLen; i++ {
+		elem := valValue.Index(i)
+		score, err := elementScore(elem, i, field)
+		if err != nil {
+			return nil, err
+		}
+		if field != nil && !isNilValue(elem) {
+			// copy the element to zero its score
+			member := reflect.New(reflect.Indirect(elem).Type()).Elem()
+			member.Set(reflect.Indirect(elem))
+			if scoreValue, ok := fieldByIndex(member, field.index); ok {
+				scoreValue.Set(reflect.Zero(scoreValue.Type()))
+			}
+			elem = member
+		}
+		member, err := toByte(elem, options)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, score, member)
+	}
+	return vals, nil
+}
+
+// elementScore returns the score of the element i of a slice, field is its ",score" field if any.
+func elementScore(elem reflect.Value, i int, field *structField) (float64, error) {
+	switch {
+	case field != nil && !isNilValue(elem):
+		if scoreValue, ok := fieldByIndex(reflect.Indirect(elem), field.index); ok {
+			return scoreOf(scoreValue)
+		}
+	case elem.Type().Implements(scorerType) && !isNilValue(elem):
+		return elem.Interface().(Scorer).Score(), nil
+	case elem.CanAddr() && elem.Addr().Type().Implements(scorerType):
+		return elem.Addr().Interface().(Scorer).Score(), nil
+	}
+	return float64(i), nil
+}
+
+func scoreOf(value reflect.Value) (float64, error) {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.Invalid:
+		// nil pointer
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%w: score of type %s", ErrUnsupportedType, value.Type())
+}
+
+func setScore(value reflect.Value, score float64) error {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(int64(score))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value.SetUint(uint64(score))
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(score)
+	default:
+		return fmt.Errorf("%w: score of type %s", ErrUnsupportedType, value.Type())
+	}
+	return nil
+}
+
+func (c *Client) setZSetValue(ctx context.Context, key string, valValue reflect.Value, options Options) (res SetResult, err error) {
+	vals, err := zsetValues(valValue, &options)
+	if err != nil {
+		return res, err
+	}
+	if len(vals) == 0 && options.WriteMode != Replace {
+		return res, nil
+	}
+	return c.writeKey(ctx, key, options, keyWrite{kind: kindZSet, args: vals})
+}
+
+// getZSetValue reads a sorted set by rank, or by score with the ScoreRange option,
+// and fills the ",score" fields with the WithScores option.
+func (c *Client) getZSetValue(ctx context.Context, key string, valValue reflect.Value, options Options) (err error) {
+	var zs []redis.Z
+	byScore := options.MinScore != "" || options.MaxScore != ""
+	if byScore {
+		by := &redis.ZRangeBy{Min: options.MinScore, Max: options.MaxScore}
+		if by.Min == "" {
+			by.Min = "-inf"
+		}
+		if by.Max == "" {
+			by.Max = "+inf"
+		}
+		if valValue.Kind() == reflect.Array {
+			by.Count = int64(valValue.Len())
+		}
+		zs, err = c.ZRangeByScoreWithScores(ctx, key, by).Result()
+	} else {
+		if options.Stop == 0 {
+			options.Stop = int64(valValue.Len() - 1)
+		}
+		zs, err = c.ZRangeWithScores(ctx, key, options.Start, options.Stop).Result()
+	}
+	if err != nil {
+		return err
+	}
+	if len(zs) == 0 {
+		if err = c.exists(ctx, key); err != nil {
+			return err
+		}
+	}
+	return decodeError(setZSet(zs, valValue, &options, options.WithScores), key)
+}
+
+// setZSet sets the members of a sorted set into a slice or an array,
+// and their scores into the ",score" fields when withScores is set.
+func setZSet(zs []redis.Z, valValue reflect.Value, options *Options, withScores bool) (err error) {
+	members := make([]string, len(zs))
+	for i, z := range zs {
+		members[i], _ = z.Member.(string)
+	}
+	if valValue.Kind() == reflect.Array {
+		if len(members) > valValue.Len() {
+			members = members[:valValue.Len()]
+		}
+		err = setArray(members, valValue, options)
+	} else {
+		err = setSlice(members, valValue, options)
+	}
+	if err != nil || !withScores {
+		return err
+	}
+	field, ok := scoreField(valValue.Type().Elem(), options)
+	if !ok {
+		return nil
+	}
+	for i := range members {
+		elem := valValue.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		err = setScore(fieldByIndexAlloc(elem, field.index), zs[i].Score)
+		if err != nil {
+			return &DecodeError{Field: field.key, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type zsetmember struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score,score"`
+}
+
+type zsetscorer struct {
+	Name string `json:"name"`
+	Rank int    `json:"rank"`
+}
+
+func (z zsetscorer) Score() float64 { return float64(-z.Rank) }
+
+func TestClient_ZSetScoreField(t *testing.T) {
+	ctx := context.Background()
+	key := "kzsetfield"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	in := []zsetmember{{"c", 3}, {"a", 1}, {"b", 2.5}}
+	err := client.SetSliceValue(ctx, key, in, RedisTypeZSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	zs, err := client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zs) != 3 || zs[0].Score != 1 || zs[0].Member != `{"name":"a","score":0}` {
+		t.Errorf("got %v", zs)
+	}
+
+	var out []zsetmember
+	err = client.GetSliceValue(ctx, key, &out, RedisTypeZSet(), WithScores())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []zsetmember{{"a", 1}, {"b", 2.5}, {"c", 3}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+
+	// without WithScores the score fields stay zero
+	var unscored []zsetmember
+	err = client.GetSliceValue(ctx, key, &unscored, RedisTypeZSet(), Range(1, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unscored, []zsetmember{{"b", 0}, {"c", 0}}) {
+		t.Errorf("got %v", unscored)
+	}
+
+	var byScore []*zsetmember
+	err = client.GetSliceValue(ctx, key, &byScore, RedisTypeZSet(), ScoreRange("(1", "3"), WithScores())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byScore) != 2 || *byScore[0] != want[1] || *byScore[1] != want[2] {
+		t.Errorf("got %v", byScore)
+	}
+
+	var array [2]zsetmember
+	err = client.GetSliceValue(ctx, key, &array, RedisTypeZSet(), ScoreRange("2", ""), WithScores())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if array != [2]zsetmember{want[1], want[2]} {
+		t.Errorf("got %v", array)
+	}
+}
+
+func TestClient_ZSetScorerAndIndex(t *testing.T) {
+	ctx := context.Background()
+	key := "kzsetscorer"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	err := client.SetSliceValue(ctx, key, []zsetscorer{{"a", 1}, {"b", 2}}, RedisTypeZSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []zsetscorer
+	err = client.GetSliceValue(ctx, key, &out, RedisTypeZSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, []zsetscorer{{"b", 2}, {"a", 1}}) {
+		t.Errorf("got %v", out)
+	}
+
+	key = "kzsetindex"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	err = client.SetSliceValue(ctx, key, []string{"x", "y", "z"}, RedisTypeZSet(), UseWriteMode(Replace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score, _ := client.ZScore(ctx, key, "z").Result(); score != 2 {
+		t.Errorf("score = %v, want 2", score)
+	}
+	var strs []string
+	err = client.GetSliceValue(ctx, key, &strs, RedisTypeZSet(), ScoreRange("1", "+inf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(strs, []string{"y", "z"}) {
+		t.Errorf("got %v", strs)
+	}
+
+	// conditional writes go through the script
+	err = client.SetSliceValue(ctx, key, []string{"w"}, RedisTypeZSet(), IfAbsent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.ZCard(ctx, key).Result(); n != 3 {
+		t.Errorf("card = %d, want 3", n)
+	}
+}